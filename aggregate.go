@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// repoPackages returns the directories below dir holding Go packages,
+// skipping vendor and testdata directories and those go ignores
+func repoPackages(dir string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			if pkgDir := filepath.Dir(path); len(dirs) == 0 || dirs[len(dirs)-1] != pkgDir {
+				dirs = append(dirs, pkgDir)
+			}
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// repoOf returns the repository holding dir, the innermost one if one is
+// nested in another, comparing the directories with their symbolic links
+// resolved
+func repoOf(dir string, repos map[string]string) string {
+	dir = realDir(dir)
+	best, bestDir := "", ""
+	for repo, repoDir := range repos {
+		repoDir = realDir(repoDir)
+		if !hasPathPrefix(dir, repoDir) {
+			continue
+		}
+		if len(repoDir) > len(bestDir) || (len(repoDir) == len(bestDir) && repo < best) {
+			best, bestDir = repo, repoDir
+		}
+	}
+	return best
+}
+
+// runAggregate scans all packages of the repositories listed in the given
+// file as "name = path" lines, paths relative to the file, and prints them as
+// one graph with a box per repository. Edges between repositories are
+// counted like those between groups. It returns the exit code.
+func runAggregate(name string) int {
+	if name == "" {
+		fatal("aggregate needs -repos")
+	}
+	repos, err := readConfig(name)
+	if err != nil {
+		fatal(err)
+	}
+	var names []string
+	for repo, dir := range repos {
+		if !filepath.IsAbs(dir) {
+			repos[repo] = filepath.Join(filepath.Dir(name), dir)
+		}
+		names = append(names, repo)
+	}
+	sort.Strings(names)
+
+	// a package reached through a symbolic link or through a repository
+	// nested in another is scanned and made a root once
+	scanned, roots := make(map[string]bool), make(map[string]bool)
+	for _, repo := range names {
+		dirs, err := repoPackages(repos[repo])
+		if err != nil {
+			fatalf("failed to read repository %s: %s", repo, err)
+		}
+		logger.Info("scanning repository", "repo", repo, "packages", len(dirs))
+		for _, dir := range dirs {
+			if scanned[realDir(dir)] {
+				continue
+			}
+			scanned[realDir(dir)] = true
+			if root, err := processPackage(canonicalDir(dir), "."); err != nil {
+				fatal(err)
+			} else if root != nil && !roots[root.ImportPath] {
+				roots[root.ImportPath] = true
+				rootPackages = append(rootPackages, root.ImportPath)
+			}
+		}
+	}
+	if len(rootPackages) == 0 {
+		fatal("no packages found in the repositories")
+	}
+
+	g, err := buildGraph()
+	if err != nil {
+		fatal(err)
+	}
+	for _, n := range g.Nodes {
+		pkg, ok := pkgs[n.ID]
+		if !ok || pkg.Goroot {
+			continue
+		}
+		n.Group = repoOf(pkg.Dir, repos)
+	}
+	if err := writeGraph(os.Stdout, g); err != nil {
+		fatal(err)
+	}
+	if len(unresolved) > 0 {
+		summarizeUnresolved()
+	}
+	cyc, err := cyclic("aggregate", cycles())
+	if err != nil {
+		fatal(err)
+	}
+	return exitStatus(false, cyc)
+}
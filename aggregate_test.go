@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoOf(t *testing.T) {
+	top := t.TempDir()
+	for _, dir := range []string{"platform/pkg", "platform/tools/gen", "web"} {
+		if err := os.MkdirAll(filepath.Join(top, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(top, "web"), filepath.Join(top, "web-link")); err != nil {
+		t.Skip(err)
+	}
+	repos := map[string]string{
+		"platform": filepath.Join(top, "platform"),
+		"tools":    filepath.Join(top, "platform", "tools"),
+		"web":      filepath.Join(top, "web-link"),
+		"web2":     filepath.Join(top, "web"),
+	}
+	tests := []struct {
+		dir, want string
+	}{
+		{"platform/pkg", "platform"},
+		{"platform/tools/gen", "tools"},
+		{"web", "web"},
+		{"web-link", "web"},
+		{".", ""},
+	}
+	for _, tt := range tests {
+		if got := repoOf(filepath.Join(top, tt.dir), repos); got != tt.want {
+			t.Errorf("repoOf(%s) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
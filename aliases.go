@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// dotImports returns the imports pkg makes into its own file scope, as in
+// import . "strings", with where they are made
+func dotImports(pkgName string) map[string]token.Position {
+	dot := make(map[string]token.Position)
+	for path, specs := range importSpecs(pkgs[pkgName]) {
+		for _, s := range specs {
+			if s.name == "." {
+				dot[path] = s.pos
+				break
+			}
+		}
+	}
+	return dot
+}
+
+// importNames returns, for every package the visible packages outside the
+// standard library import, the names they give it and who gives which. An
+// import without alias uses the package name.
+func importNames() map[string]map[string][]string {
+	names := make(map[string]map[string][]string)
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot {
+			continue
+		}
+		for imp, specs := range importSpecs(pkg) {
+			for _, s := range specs {
+				name := s.name
+				if name == "_" || name == "." {
+					continue
+				}
+				if name == "" {
+					name = packageName(imp)
+				}
+				if names[imp] == nil {
+					names[imp] = make(map[string][]string)
+				}
+				names[imp][name] = append(names[imp][name], pkgName)
+			}
+		}
+	}
+	return names
+}
+
+// packageName returns the name of a package, guessed from its import path if
+// it was not loaded
+func packageName(imp string) string {
+	if pkg, ok := pkgs[imp]; ok && pkg.Name != "" {
+		return pkg.Name
+	}
+	return path.Base(imp)
+}
+
+// inconsistentAliases returns the packages that are imported under more than
+// one name
+func inconsistentAliases() map[string]map[string][]string {
+	inconsistent := make(map[string]map[string][]string)
+	for imp, byName := range importNames() {
+		if len(byName) > 1 {
+			inconsistent[imp] = byName
+		}
+	}
+	return inconsistent
+}
+
+// printAliases reports the dot imports of all visible packages and the
+// packages imported under different names
+func printAliases() error {
+	for _, pkgName := range visiblePackages() {
+		if pkgs[pkgName].Goroot {
+			continue
+		}
+		dot := dotImports(pkgName)
+		var imps []string
+		for imp := range dot {
+			imps = append(imps, imp)
+		}
+		sort.Strings(imps)
+		for _, imp := range imps {
+			pos := dot[imp]
+			fmt.Printf("%s: . %s (%s/%s:%d)\n", pkgName, imp, pkgName, path.Base(pos.Filename), pos.Line)
+		}
+	}
+
+	inconsistent := inconsistentAliases()
+	var imps []string
+	for imp := range inconsistent {
+		imps = append(imps, imp)
+	}
+	sort.Strings(imps)
+	for _, imp := range imps {
+		var names []string
+		for name := range inconsistent[imp] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var parts []string
+		for _, name := range names {
+			importers := sortedSet(inconsistent[imp][name])
+			parts = append(parts, fmt.Sprintf("as %s by %s", name, strings.Join(importers, ", ")))
+		}
+		fmt.Printf("%s: imported %s\n", imp, strings.Join(parts, "; "))
+	}
+	return nil
+}
+
+// aliasEdgeAttrs marks dot imports, and imports using one of several names
+// the package goes by
+func aliasEdgeAttrs(pkgName, imp string, inconsistent map[string]map[string][]string) attrs {
+	if pkgs[pkgName].Goroot {
+		return nil
+	}
+	if _, ok := dotImports(pkgName)[imp]; ok {
+		return attrs{"color": "orange", "style": "bold", "label": ".", "tooltip": "dot import"}
+	}
+	for name, importers := range inconsistent[imp] {
+		for _, importer := range importers {
+			if importer == pkgName {
+				return attrs{"color": "orange", "label": name, "tooltip": "imported under several names"}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// anonymizeSecret keys the hashes of -anonymize: -anonymize-key, or
+	// random bytes drawn once per run
+	anonymizeSecret []byte
+	secretOnce      sync.Once
+)
+
+// anonymizePath replaces every element of an import path by a short keyed
+// hash of it, so paths sharing a prefix still do after anonymizing. Without
+// the key, the hashes cannot be reversed by hashing common names; they are
+// only the same across runs given the same -anonymize-key.
+func anonymizePath(path string) string {
+	if path == "" {
+		return ""
+	}
+	secretOnce.Do(func() {
+		if *anonymizeKey != "" {
+			anonymizeSecret = []byte(*anonymizeKey)
+			return
+		}
+		anonymizeSecret = make([]byte, 32)
+		if _, err := rand.Read(anonymizeSecret); err != nil {
+			fatalf("failed to draw anonymization key: %s", err)
+		}
+	})
+	elems := strings.Split(path, "/")
+	for i, elem := range elems {
+		mac := hmac.New(sha256.New, anonymizeSecret)
+		mac.Write([]byte(elem))
+		elems[i] = fmt.Sprintf("%x", mac.Sum(nil))[:8]
+	}
+	return strings.Join(elems, "/")
+}
+
+// anonymize replaces all import paths of the graph except those of the
+// standard library, and drops everything else that might give away names:
+// errors, group names, categories, import declarations, descriptions and
+// links from -metadata, the labels and tooltips of overlays, which hold the
+// owners, and the notes of what the filters and views left out.
+func (g *graph) anonymize() {
+	stdlib := make(map[string]bool)
+	for _, n := range g.Nodes {
+		stdlib[n.ID] = n.Stdlib
+	}
+	id := func(path string) string {
+		if stdlib[path] {
+			return path
+		}
+		return anonymizePath(path)
+	}
+	scrub := func(a attrs) {
+		delete(a, "label")
+		delete(a, "tooltip")
+		delete(a, "URL")
+		delete(a, "href")
+	}
+
+	g.Namespace = anonymizePath(g.Namespace)
+	g.Simplifications = nil
+	for i, root := range g.Roots {
+		g.Roots[i] = id(root)
+	}
+	for _, n := range g.Nodes {
+		n.ID = id(n.ID)
+		n.Namespace = anonymizePath(n.Namespace)
+		n.Module = anonymizePath(n.Module)
+		n.Group = anonymizePath(n.Group)
+		if n.Error != "" {
+			n.Error = "unresolved"
+		}
+		n.Description, n.URL = "", ""
+		n.Category = ""
+		scrub(n.Attrs)
+	}
+	for _, e := range g.Edges {
+		e.Namespace = anonymizePath(e.Namespace)
+		e.From, e.To = id(e.From), id(e.To)
+		e.Sources = nil
+		scrub(e.Attrs)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeLeavesNoNames(t *testing.T) {
+	g := &graph{
+		Namespace: "example.com/secret",
+		Roots:     []string{"example.com/secret/app"},
+		Nodes: []*node{
+			{
+				ID:          "example.com/secret/app",
+				Module:      "example.com/secret",
+				Group:       "Payments",
+				Category:    "secret-team",
+				Description: "billing of secret customers",
+				URL:         "https://wiki.secret.com/app",
+				Attrs: attrs{
+					"label":   "(alice@secret.com)",
+					"tooltip": "90% of the lines by alice@secret.com",
+					"URL":     "https://wiki.secret.com/app",
+					"href":    "https://wiki.secret.com/app",
+				},
+			},
+			{ID: "fmt", Stdlib: true, Attrs: attrs{}},
+		},
+		Edges:           []*edge{{From: "example.com/secret/app", To: "fmt", Attrs: attrs{"tooltip": "secret"}}},
+		Simplifications: []string{"-focus-file hid example.com/secret/internal"},
+	}
+	g.anonymize()
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"secret", "Payments", "alice", "wiki"} {
+		if strings.Contains(string(data), name) {
+			t.Errorf("anonymized graph still contains %q: %s", name, data)
+		}
+	}
+	if g.Nodes[1].ID != "fmt" {
+		t.Errorf("standard library package renamed to %s", g.Nodes[1].ID)
+	}
+}
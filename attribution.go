@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// attribution tells which own packages bring an external module in
+type attribution struct {
+	module    string
+	importers []string
+	// paths counts the import chains from the roots to the module that do
+	// not pass through it before
+	paths int
+}
+
+// attributions returns the attribution of every external module, the ones
+// imported by most of the own packages first
+func (g *graph) attributions() []*attribution {
+	own := g.ownModule()
+	moduleOf := make(map[string]string)
+	for _, n := range g.Nodes {
+		if !n.Stdlib && n.Module != "" {
+			moduleOf[n.ID] = n.Module
+		}
+	}
+	next := g.imports()
+
+	var list []*attribution
+	for _, mod := range g.externalModules() {
+		a := &attribution{module: mod}
+		seen := make(map[string]bool)
+		for _, e := range g.Edges {
+			if moduleOf[e.From] == own && moduleOf[e.To] == mod && !seen[e.From] {
+				seen[e.From] = true
+				a.importers = append(a.importers, e.From)
+			}
+		}
+		sort.Strings(a.importers)
+
+		// memoized per package, edges back into a chain in progress are
+		// left out so that cycles do not count forever
+		count := make(map[string]int)
+		state := make(map[string]int) // 0 new, 1 in progress, 2 done
+		var paths func(v string) int
+		paths = func(v string) int {
+			if moduleOf[v] == mod {
+				return 1
+			}
+			if state[v] != 0 {
+				return count[v]
+			}
+			state[v] = 1
+			for _, w := range next(v) {
+				if state[w] != 1 {
+					count[v] += paths(w)
+				}
+			}
+			state[v] = 2
+			return count[v]
+		}
+		for _, root := range g.Roots {
+			a.paths += paths(root)
+		}
+		list = append(list, a)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if len(list[i].importers) != len(list[j].importers) {
+			return len(list[i].importers) > len(list[j].importers)
+		}
+		return list[i].paths > list[j].paths
+	})
+	return list
+}
+
+// printAttributions lists the external modules with the own packages that
+// import them directly and the number of import chains leading to them
+func printAttributions(g *graph) error {
+	for _, a := range g.attributions() {
+		fmt.Printf("%s: %d %s, %d %s\n", a.module,
+			len(a.importers), choose(len(a.importers) == 1, "importer", "importers"),
+			a.paths, choose(a.paths == 1, "path", "paths"))
+		for _, imp := range a.importers {
+			fmt.Printf("    %s\n", imp)
+		}
+	}
+	return nil
+}
+
+// introductions returns the shortest import chain from the roots to a
+// package of every external module, found by a breadth-first search
+func (g *graph) introductions() map[string][]string {
+	moduleOf := make(map[string]string)
+	for _, n := range g.Nodes {
+		if !n.Stdlib && n.Module != "" {
+			moduleOf[n.ID] = n.Module
+		}
+	}
+	external := stringSet(g.externalModules())
+	next := g.imports()
+
+	chains := make(map[string][]string)
+	parent := make(map[string]string)
+	seen := make(map[string]bool)
+	queue := append([]string(nil), g.Roots...)
+	for _, root := range g.Roots {
+		seen[root] = true
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if mod := moduleOf[v]; external[mod] {
+			if _, ok := chains[mod]; !ok {
+				var chain []string
+				for p := v; p != ""; p = parent[p] {
+					chain = append([]string{p}, chain...)
+				}
+				chains[mod] = chain
+			}
+		}
+		for _, w := range next(v) {
+			if !seen[w] {
+				seen[w] = true
+				parent[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return chains
+}
+
+// printIntroductions prints the shortest import chain introducing every
+// external module, one line per module
+func printIntroductions(g *graph) error {
+	chains := g.introductions()
+	for _, mod := range g.externalModules() {
+		if chain, ok := chains[mod]; ok {
+			fmt.Printf("%s: %s\n", mod, strings.Join(chain, " -> "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// vuln is an entry of the Go vulnerability database index for a module
+type vuln struct {
+	ID string `json:"id"`
+	// Introduced is the first affected version, empty if all are
+	Introduced string `json:"introduced"`
+	// Fixed is the first version with the fix, empty if there is none
+	Fixed string `json:"fixed"`
+}
+
+// affects reports whether the version is in [Introduced, Fixed)
+func (v vuln) affects(current semver) bool {
+	if introduced, ok := parseSemver(v.Introduced); ok && current.less(introduced) {
+		return false
+	}
+	fixed, ok := parseSemver(v.Fixed)
+	return !ok || current.less(fixed)
+}
+
+// vulnDB returns the vulnerability database to query, GOVULNDB like
+// govulncheck or the public one
+func vulnDB() string {
+	if db := os.Getenv("GOVULNDB"); db != "" {
+		return strings.TrimSuffix(db, "/")
+	}
+	return "https://vuln.go.dev"
+}
+
+// loadVulns fetches the module index of the vulnerability database and
+// returns the vulnerabilities affecting the given module versions: the ones
+// introduced at or before them and not fixed in them
+func loadVulns(versions map[string]string) (map[string][]vuln, error) {
+	resp, err := proxyClient.Get(vulnDB() + "/index/modules.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vulnerability database: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query vulnerability database: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vulnerability database: %s", err)
+	}
+	var index []struct {
+		Path  string `json:"path"`
+		Vulns []vuln `json:"vulns"`
+	}
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode vulnerability database index: %s", err)
+	}
+
+	found := make(map[string][]vuln)
+	for _, entry := range index {
+		current, ok := parseSemver(versions[entry.Path])
+		if !ok {
+			continue
+		}
+		for _, v := range entry.Vulns {
+			if v.affects(current) {
+				found[entry.Path] = append(found[entry.Path], v)
+			}
+		}
+	}
+	return found, nil
+}
+
+// licenseKinds maps phrases of license texts to the licenses they identify,
+// the more specific ones first
+var licenseKinds = []struct{ phrase, license string }{
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL"},
+	{"GNU GENERAL PUBLIC LICENSE", "GPL"},
+	{"Mozilla Public License", "MPL-2.0"},
+	{"Apache License", "Apache-2.0"},
+	{"Permission is hereby granted, free of charge", "MIT"},
+	{"Redistribution and use in source and binary forms", "BSD"},
+	{"Permission to use, copy, modify, and/or distribute", "ISC"},
+	{"This is free and unencumbered software", "Unlicense"},
+}
+
+// copyleft holds the licenses that put conditions on the code linking them
+var copyleft = map[string]bool{"AGPL": true, "GPL": true, "LGPL": true, "MPL-2.0": true}
+
+// moduleLicense identifies the license in the root directory of the module
+// of pkg, "unknown" for a license text it does not recognize and "" if there
+// is none
+func moduleLicense(pkg *build.Package, mod string) string {
+	dir := pkg.Dir
+	for n := strings.Count(pkg.ImportPath, "/") - strings.Count(mod, "/"); n > 0; n-- {
+		dir = filepath.Dir(dir)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	for _, name := range files {
+		base := strings.ToUpper(filepath.Base(name))
+		if !strings.HasPrefix(base, "LICENSE") && !strings.HasPrefix(base, "LICENCE") && !strings.HasPrefix(base, "COPYING") {
+			continue
+		}
+		text, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		for _, kind := range licenseKinds {
+			if strings.Contains(string(text), kind.phrase) {
+				return kind.license
+			}
+		}
+		return "unknown"
+	}
+	return ""
+}
+
+// finding is an issue of a module in the audit, weighted by how urgent it is
+type finding struct {
+	weight int
+	text   string
+}
+
+// runAudit prints the external modules with version lag, deprecation,
+// license and vulnerability findings, the most pressing first: the weights
+// of the findings of a module are multiplied by the number of own packages
+// importing it
+func runAudit(g *graph) error {
+	versions := make(map[string]string)
+	if len(rootPackages) > 0 {
+		if name := goModFile(pkgs[rootPackages[0]].Dir); name != "" {
+			var err error
+			if versions, err = requiredVersions(name); err != nil {
+				return fmt.Errorf("failed to read %s: %s", name, err)
+			}
+		}
+	}
+	lags, err := loadOutdated()
+	if err != nil {
+		return err
+	}
+	deprecated := loadDeprecations(g.externalModules())
+	vulns, err := loadVulns(versions)
+	if err != nil {
+		logger.Warn("skipped vulnerability check", "err", err)
+	}
+
+	type entry struct {
+		*attribution
+		findings []finding
+		priority int
+	}
+	var entries []*entry
+	for _, a := range g.attributions() {
+		e := &entry{attribution: a}
+		for _, v := range vulns[a.module] {
+			e.findings = append(e.findings, finding{10, fmt.Sprintf("vulnerable: %s%s", v.ID, choose(v.Fixed != "", ", fixed in v"+strings.TrimPrefix(v.Fixed, "v"), ", not fixed yet"))})
+		}
+		if msg, ok := deprecated[a.module]; ok {
+			e.findings = append(e.findings, finding{5, "deprecated: " + msg})
+		}
+		if l, ok := lags[a.module]; ok {
+			weight := map[string]int{"major": 3, "minor": 2, "patch": 1}[l.level]
+			e.findings = append(e.findings, finding{weight, fmt.Sprintf("%s update available: %s -> %s", l.level, l.required, l.latest)})
+		}
+		for _, n := range g.Nodes {
+			if pkg := pkgs[n.ID]; pkg != nil && n.Module == a.module {
+				switch license := moduleLicense(pkg, a.module); {
+				case license == "":
+					e.findings = append(e.findings, finding{3, "license: none found"})
+				case license == "unknown":
+					e.findings = append(e.findings, finding{2, "license: not recognized"})
+				case copyleft[license]:
+					e.findings = append(e.findings, finding{3, "license: " + license + ", copyleft"})
+				}
+				break
+			}
+		}
+		for _, f := range e.findings {
+			e.priority += f.weight * (1 + len(a.importers))
+		}
+		if len(e.findings) > 0 {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].priority > entries[j].priority })
+
+	for _, e := range entries {
+		version := versions[e.module]
+		fmt.Printf("%s%s: priority %d, %d %s, %d %s\n", e.module, choose(version != "", " "+version, ""), e.priority,
+			len(e.importers), choose(len(e.importers) == 1, "importer", "importers"),
+			e.paths, choose(e.paths == 1, "path", "paths"))
+		sort.SliceStable(e.findings, func(i, j int) bool { return e.findings[i].weight > e.findings[j].weight })
+		for _, f := range e.findings {
+			fmt.Printf("    %s\n", f.text)
+		}
+	}
+	return nil
+}
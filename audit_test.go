@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestVulnAffects(t *testing.T) {
+	tests := []struct {
+		introduced, fixed, version string
+		want                       bool
+	}{
+		{"", "v1.4.5", "v1.4.2", true},
+		{"", "v1.4.5", "v1.4.5", false},
+		{"", "", "v9.0.0", true},
+		{"v1.2.0", "v1.4.5", "v1.1.9", false},
+		{"v1.2.0", "v1.4.5", "v1.2.0", true},
+		{"v1.2.0", "v1.4.5", "v1.3.0", true},
+		{"v1.2.0", "v1.4.5", "v1.4.6", false},
+		{"v1.2.0", "", "v1.0.0", false},
+		{"v1.2.0", "", "v2.0.0", true},
+		{"v1.2.0", "v1.4.5", "v1.2.0-rc.1", false},
+	}
+	for _, tt := range tests {
+		current, ok := parseSemver(tt.version)
+		if !ok {
+			t.Fatalf("invalid version %s", tt.version)
+		}
+		v := vuln{ID: "GO-2024-1234", Introduced: tt.introduced, Fixed: tt.fixed}
+		if got := v.affects(current); got != tt.want {
+			t.Errorf("[%s, %s) affects %s = %v, want %v", tt.introduced, tt.fixed, tt.version, got, tt.want)
+		}
+	}
+}
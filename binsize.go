@@ -0,0 +1,180 @@
+package main
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type symbol struct {
+	name    string
+	section int
+	addr    uint64
+	size    uint64
+}
+
+// loadBinarySizes reads the symbol table of an ELF, Mach-O or PE binary and
+// sums up the symbol sizes per loaded package, keyed by import path.
+func loadBinarySizes(name string) (map[string]int64, error) {
+	syms, err := readSymbols(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbols of %s: %s", name, err)
+	}
+	fillSymbolSizes(syms)
+	main, err := mainPackage()
+	if err != nil {
+		return nil, err
+	}
+	if main != "" {
+		logger.Info("attributing the symbols of package main", "pkg", main)
+	}
+
+	sizes := make(map[string]int64)
+	var unattributed uint64
+	for _, s := range syms {
+		if path := symbolPackage(s.name, main); path != "" {
+			sizes[path] += int64(s.size)
+		} else {
+			unattributed += s.size
+		}
+	}
+	if len(sizes) == 0 {
+		logger.Warn("no symbols of the graph's packages found, is the binary stripped?", "binary", name)
+	}
+	logger.Info("symbols not attributed to any package", "bytes", unattributed)
+	return sizes, nil
+}
+
+func readSymbols(name string) ([]symbol, error) {
+	if f, err := elf.Open(name); err == nil {
+		defer f.Close()
+		elfSyms, err := f.Symbols()
+		if err != nil {
+			return nil, err
+		}
+		syms := make([]symbol, len(elfSyms))
+		for i, s := range elfSyms {
+			syms[i] = symbol{s.Name, int(s.Section), s.Value, s.Size}
+		}
+		return syms, nil
+	}
+	if f, err := macho.Open(name); err == nil {
+		defer f.Close()
+		if f.Symtab == nil {
+			return nil, fmt.Errorf("no symbol table")
+		}
+		syms := make([]symbol, len(f.Symtab.Syms))
+		for i, s := range f.Symtab.Syms {
+			syms[i] = symbol{s.Name, int(s.Sect), s.Value, 0}
+		}
+		return syms, nil
+	}
+	if f, err := pe.Open(name); err == nil {
+		defer f.Close()
+		syms := make([]symbol, len(f.Symbols))
+		for i, s := range f.Symbols {
+			syms[i] = symbol{s.Name, int(s.SectionNumber), uint64(s.Value), 0}
+		}
+		return syms, nil
+	}
+	return nil, fmt.Errorf("unknown binary format")
+}
+
+// fillSymbolSizes approximates missing sizes (Mach-O and PE do not record
+// them) by the distance to the next symbol in the same section.
+func fillSymbolSizes(syms []symbol) {
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].section != syms[j].section {
+			return syms[i].section < syms[j].section
+		}
+		return syms[i].addr < syms[j].addr
+	})
+	for i := range syms {
+		if syms[i].size != 0 || i+1 == len(syms) || syms[i+1].section != syms[i].section {
+			continue
+		}
+		syms[i].size = syms[i+1].addr - syms[i].addr
+	}
+}
+
+// symbolPackage maps a Go symbol name like "github.com/foo/bar.(*T).Method"
+// to the import path of the loaded package defining it, or symbols of
+// package main to the import path of main.
+func symbolPackage(name, main string) string {
+	for _, prefix := range []string{"type:", "type.", "go:itab.", "go.itab.", "go:"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	name = strings.TrimPrefix(name, "*")
+
+	// the package path ends at some dot after the last slash, but the last
+	// path element may contain dots itself (gopkg.in/yaml.v2)
+	start := strings.LastIndex(name, "/") + 1
+	for i := start; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		path := name[:i]
+		if _, ok := pkgs[path]; ok {
+			return path
+		}
+		if path == "main" {
+			return main
+		}
+	}
+	return ""
+}
+
+// mainPackage returns the import path of the loaded main package, since the
+// linker names its symbols "main." instead of using the import path. A
+// binary is built from one main package, so with several loaded there is no
+// telling which one it is.
+func mainPackage() (string, error) {
+	var mains []string
+	for path, pkg := range pkgs {
+		if pkg.Name == "main" {
+			mains = append(mains, path)
+		}
+	}
+	sort.Strings(mains)
+	switch len(mains) {
+	case 0:
+		return "", nil
+	case 1:
+		return mains[0], nil
+	}
+	return "", fmt.Errorf("-binary measures one binary, but %d main packages are loaded: %s. Pass only the one it was built from", len(mains), strings.Join(mains, ", "))
+}
+
+// binarySizeAttrs scales the node with its share of the biggest package
+func binarySizeAttrs(pkgName string, sizes map[string]int64) attrs {
+	size, ok := sizes[pkgName]
+	if !ok {
+		return nil
+	}
+	var max int64 = 1
+	for _, v := range sizes {
+		if v > max {
+			max = v
+		}
+	}
+	scale := float64(size) / float64(max)
+	return attrs{
+		"label":   fmt.Sprintf("(%s)", humanBytes(size)),
+		"width":   fmt.Sprintf("%.2f", 0.75+3*scale),
+		"height":  fmt.Sprintf("%.2f", 0.5+1.5*scale),
+		"tooltip": fmt.Sprintf("%d bytes in binary", size),
+	}
+}
+
+func humanBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
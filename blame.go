@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ownerColors are assigned to the owners of packages in sorted order
+var ownerColors = []string{
+	"lightpink", "lightsalmon", "khaki", "lightblue", "plum",
+	"aquamarine", "wheat", "lightcoral", "thistle", "palegoldenrod",
+}
+
+// ownership is the predominant author or team of each package, by the lines
+// git blame attributes to them
+type ownership struct {
+	owner  map[string]string
+	share  map[string]float64
+	colors map[string]string
+}
+
+// loadOwnership blames the files of every visible package in a git work tree.
+// Authors are identified by email and, if teams is not empty, mapped to the
+// team it names for them; authors without a team count as themselves.
+func loadOwnership(teams string) (*ownership, error) {
+	var team map[string]string
+	if teams != "" {
+		var err error
+		if team, err = readConfig(teams); err != nil {
+			return nil, err
+		}
+	}
+
+	o := &ownership{
+		owner:  make(map[string]string),
+		share:  make(map[string]float64),
+		colors: make(map[string]string),
+	}
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot || gitTopLevel(pkg.Dir) == "" {
+			continue
+		}
+		lines := make(map[string]int)
+		total := 0
+		for _, name := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+			output, err := git(pkg.Dir, "blame", "--line-porcelain", "--", name)
+			if err != nil {
+				// most likely not committed yet
+				logger.Debug("failed to blame file", "pkg", pkgName, "file", name, "err", err)
+				continue
+			}
+			s := bufio.NewScanner(bytes.NewReader(output))
+			for s.Scan() {
+				if !strings.HasPrefix(s.Text(), "author-mail ") {
+					continue
+				}
+				author := strings.Trim(strings.TrimPrefix(s.Text(), "author-mail "), "<>")
+				if t, ok := team[author]; ok {
+					author = t
+				}
+				lines[author]++
+				total++
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		best := ""
+		for author, n := range lines {
+			if best == "" || n > lines[best] || (n == lines[best] && author < best) {
+				best = author
+			}
+		}
+		o.owner[pkgName] = best
+		o.share[pkgName] = float64(lines[best]) / float64(total)
+	}
+
+	var owners []string
+	for _, owner := range o.owner {
+		if _, ok := o.colors[owner]; !ok {
+			o.colors[owner] = ""
+			owners = append(owners, owner)
+		}
+	}
+	sort.Strings(owners)
+	for i, owner := range owners {
+		o.colors[owner] = ownerColors[i%len(ownerColors)]
+	}
+	return o, nil
+}
+
+func (o *ownership) nodeAttrs(pkgName string) attrs {
+	owner, ok := o.owner[pkgName]
+	if !ok {
+		return nil
+	}
+	return attrs{
+		"label":     fmt.Sprintf("(%s)", owner),
+		"fillcolor": o.colors[owner],
+		"tooltip":   fmt.Sprintf("%.0f%% of the lines by %s", 100*o.share[pkgName], owner),
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// importSpec is an import declaration: the name it gives the package, ""
+// for none, and where it is
+type importSpec struct {
+	name string
+	pos  token.Position
+}
+
+// specCache holds the import declarations per import path, see importSpecs
+var specCache = make(map[string]map[string][]importSpec)
+
+// importSpecs returns the import declarations of the files of pkg, by import
+// path
+func importSpecs(pkg *build.Package) map[string][]importSpec {
+	if specs, ok := specCache[pkg.ImportPath]; ok {
+		return specs
+	}
+	specs := parseImportSpecs(pkg, append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...))
+	specCache[pkg.ImportPath] = specs
+	return specs
+}
+
+// parseImportSpecs returns the import declarations of the given files of
+// pkg, by import path
+func parseImportSpecs(pkg *build.Package, files []string) map[string][]importSpec {
+	specs := make(map[string][]importSpec)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			logger.Warn("failed to parse imports", "pkg", pkg.ImportPath, "err", err)
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			s := importSpec{pos: fset.Position(spec.Pos())}
+			if spec.Name != nil {
+				s.name = spec.Name.Name
+			}
+			specs[path] = append(specs[path], s)
+		}
+	}
+	return specs
+}
+
+// blankImports returns the imports pkg only makes for their side effects, as
+// in import _ "image/png", with where they are made. An import that some file
+// uses by name is not blank.
+func blankImports(pkg *build.Package) map[string]token.Position {
+	return blankSpecs(importSpecs(pkg))
+}
+
+// blankSpecs returns the imports among the declarations that are all blank
+func blankSpecs(declared map[string][]importSpec) map[string]token.Position {
+	blank := make(map[string]token.Position)
+	for path, specs := range declared {
+		named := false
+		for _, s := range specs {
+			named = named || s.name != "_"
+		}
+		if !named {
+			blank[path] = specs[0].pos
+		}
+	}
+	return blank
+}
+
+// isBlankImport reports whether pkg imports imp only for its side effects
+func isBlankImport(pkg *build.Package, imp string) bool {
+	if pkg.Goroot && !*stdlibEdges {
+		return false
+	}
+	_, ok := blankImports(pkg)[imp]
+	return ok
+}
+
+// printBlankImports lists the blank imports of all visible packages, one per
+// line with the file making it. Imports of filtered packages are listed too.
+func printBlankImports() error {
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot && !*stdlibEdges {
+			continue
+		}
+		blank := blankImports(pkg)
+		var imps []string
+		for imp := range blank {
+			imps = append(imps, imp)
+		}
+		sort.Strings(imps)
+		for _, imp := range imps {
+			pos := blank[imp]
+			fmt.Printf("%s: _ %s (%s/%s:%d)\n", pkgName, imp, pkgName, filepath.Base(pos.Filename), pos.Line)
+		}
+	}
+	return nil
+}
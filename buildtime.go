@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// action is an entry of the JSON written by go build -debug-actiongraph
+type action struct {
+	ID        int
+	Mode      string
+	Package   string
+	Deps      []int
+	TimeStart time.Time
+	TimeDone  time.Time
+}
+
+func (a *action) duration() time.Duration {
+	if a.TimeStart.IsZero() || a.TimeDone.IsZero() {
+		return 0
+	}
+	return a.TimeDone.Sub(a.TimeStart)
+}
+
+// buildTimes is the compile time attribution read from an action graph
+type buildTimes struct {
+	perPackage map[string]time.Duration
+	// critical holds the packages on the longest chain of dependent actions,
+	// and criticalEdges the import edges connecting them
+	critical      map[string]bool
+	criticalEdges map[[2]string]bool
+}
+
+// loadBuildTimes reads the action graph of a go build run
+func loadBuildTimes(name string) (*buildTimes, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open action graph: %s", err)
+	}
+	defer f.Close()
+
+	var actions []*action
+	if err := json.NewDecoder(f).Decode(&actions); err != nil {
+		return nil, fmt.Errorf("failed to decode action graph: %s", err)
+	}
+
+	bt := &buildTimes{
+		perPackage:    make(map[string]time.Duration),
+		critical:      make(map[string]bool),
+		criticalEdges: make(map[[2]string]bool),
+	}
+	byID := make(map[int]*action)
+	for _, a := range actions {
+		byID[a.ID] = a
+		// linking is attributed to the whole binary, not the main package
+		if a.Package != "" && a.Mode != "link" && a.Mode != "link-install" {
+			bt.perPackage[a.Package] += a.duration()
+		}
+	}
+
+	// longest chain of actions by accumulated duration, memoized per action
+	finish := make(map[int]time.Duration)
+	next := make(map[int]int)
+	var longest func(a *action) time.Duration
+	longest = func(a *action) time.Duration {
+		if d, ok := finish[a.ID]; ok {
+			return d
+		}
+		finish[a.ID] = 0 // guards against malformed cyclic input
+		var best time.Duration
+		next[a.ID] = -1
+		for _, id := range a.Deps {
+			if dep, ok := byID[id]; ok {
+				if d := longest(dep); d > best || next[a.ID] == -1 {
+					best, next[a.ID] = d, id
+				}
+			}
+		}
+		finish[a.ID] = best + a.duration()
+		return finish[a.ID]
+	}
+
+	var top *action
+	for _, a := range actions {
+		if top == nil || longest(a) > longest(top) {
+			top = a
+		}
+	}
+	prev := ""
+	for a := top; a != nil; a = byID[next[a.ID]] {
+		if a.Package == "" || a.Package == prev {
+			continue
+		}
+		bt.critical[a.Package] = true
+		if prev != "" {
+			bt.criticalEdges[[2]string{prev, a.Package}] = true
+		}
+		prev = a.Package
+	}
+	return bt, nil
+}
+
+func (bt *buildTimes) nodeAttrs(pkgName string) attrs {
+	d, ok := bt.perPackage[pkgName]
+	if !ok {
+		return nil
+	}
+	a := attrs{
+		"label":   fmt.Sprintf("(build %s)", d.Round(time.Millisecond)),
+		"tooltip": fmt.Sprintf("compile time %s", d),
+	}
+	if bt.critical[pkgName] {
+		a["color"] = "red"
+		a["penwidth"] = "3"
+	}
+	return a
+}
+
+func (bt *buildTimes) edgeAttrs(source, dest string) attrs {
+	if !bt.criticalEdges[[2]string{source, dest}] {
+		return nil
+	}
+	return attrs{"color": "red", "penwidth": "2"}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// failureCause guesses the underlying cause of a resolution failure, so that
+// the packages of one missing module are reported together. The module is
+// guessed from the import path like the repository of -churn.
+func failureCause(pkgName string, err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "missing go.sum entry"):
+		return "missing go.sum entry for " + repoRoot(pkgName)
+	case strings.Contains(msg, "build constraints exclude all Go files"),
+		strings.Contains(msg, "no buildable Go source files"):
+		return "build constraints excluding " + pkgName
+	case strings.Contains(msg, "cannot find package"),
+		strings.Contains(msg, "no required module provides package"),
+		strings.Contains(msg, "is not in std"),
+		strings.Contains(msg, "cannot find module providing package"):
+		if isStdlib(pkgName) {
+			return "missing standard library package " + pkgName
+		}
+		return "missing module " + repoRoot(pkgName)
+	}
+	// otherwise the error itself, without the package it is about
+	first := strings.SplitN(msg, "\n", 2)[0]
+	return strings.Replace(strings.TrimPrefix(first, fmt.Sprintf("failed to import %s: ", pkgName)), pkgName, "<pkg>", -1)
+}
+
+// failureCauses groups the packages that failed to resolve by cause, the
+// causes of most failures first
+func failureCauses() ([]string, map[string][]string) {
+	members := make(map[string][]string)
+	for name, err := range unresolved {
+		cause := failureCause(name, err)
+		members[cause] = append(members[cause], name)
+	}
+	var causes []string
+	for cause, names := range members {
+		sort.Strings(names)
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		if len(members[causes[i]]) != len(members[causes[j]]) {
+			return len(members[causes[i]]) > len(members[causes[j]])
+		}
+		return causes[i] < causes[j]
+	})
+	return causes, members
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runCheck compares the graph against the budgets given by the -max-* flags
+// and prints one line per budget. External modules and cycles exempted by
+// -exemptions do not count, and each exemption gets a line as well. With
+// -allowed, dependencies missing from the manifest fail the check too. It
+// returns false if any budget is exceeded, an exemption in use expired or
+// there are new dependencies.
+func runCheck(g *graph) (bool, error) {
+	exemptions := make(map[string]*exemption)
+	if *exemptionsFile != "" {
+		var err error
+		if exemptions, err = loadExemptions(*exemptionsFile); err != nil {
+			return false, err
+		}
+	}
+
+	ok := true
+	budget := func(name string, value, max int, detail string) {
+		if max < 0 {
+			return
+		}
+		status := "ok"
+		if value > max {
+			status = "FAIL"
+			ok = false
+		}
+		fmt.Printf("%-4s %s: %d (max %d)%s\n", status, name, value, max, detail)
+	}
+
+	var external []string
+	for _, mod := range g.externalModules() {
+		if exempt(exemptions, mod) == nil {
+			external = append(external, mod)
+		}
+	}
+	detail := ""
+	if len(external) > 0 {
+		detail = "\n     " + strings.Join(external, "\n     ")
+	}
+	budget("external modules", len(external), *maxExternal, detail)
+
+	maxRootDepth := 0
+	for _, root := range g.Roots {
+		if d := g.depth(root); d > maxRootDepth {
+			maxRootDepth = d
+		}
+	}
+	budget("depth", maxRootDepth, *maxDepth, "")
+
+	detail = ""
+	var cycs [][]string
+	for _, c := range g.cycles() {
+		if exempt(exemptions, c...) == nil {
+			cycs = append(cycs, c)
+		}
+	}
+	for _, c := range cycs {
+		detail += "\n     " + strings.Join(c, " <-> ")
+	}
+	budget("cycles", len(cycs), *maxCycles, detail)
+
+	if *allowedFile != "" {
+		m, err := loadManifest(*allowedFile)
+		if err != nil {
+			return false, err
+		}
+		modules, imports, unused := m.unfrozen(g)
+		status := "ok"
+		if len(modules)+len(imports) > 0 {
+			status = "FAIL"
+			ok = false
+		}
+		fmt.Printf("%-4s new dependencies: %d (%d allowed %s no longer used)\n", status, len(modules)+len(imports), unused, choose(unused == 1, "one", "ones"))
+		for _, mod := range modules {
+			fmt.Printf("     module %s\n", mod)
+		}
+		for _, imp := range imports {
+			fmt.Printf("     import %s\n", imp)
+		}
+	}
+
+	return reportExemptions(exemptions) && ok, nil
+}
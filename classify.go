@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// classifier assigns the packages categories of the user's own taxonomy and
+// says how every category is drawn
+type classifier struct {
+	// rules map import paths, prefixes or glob patterns to categories
+	rules  map[string]string
+	styles map[string]categoryStyle
+}
+
+// categoryStyle is how the packages of a category are drawn
+type categoryStyle struct {
+	color, shape string
+	// cluster draws the packages of the category in one box
+	cluster bool
+}
+
+// loadClassifier reads a file of "pattern = category" lines, where a
+// pattern is an import path, a prefix or a glob and std stands for the
+// standard library, followed by a section per category setting its color,
+// shape and whether its packages are drawn in one box:
+//
+//	github.com/foo/app/internal = internal
+//	github.com/foo/app/**/mock* = testing
+//
+//	[internal]
+//	color = lightblue
+//	shape = box
+//	cluster = true
+func loadClassifier(name string) (*classifier, error) {
+	sections, err := readSections(name)
+	if err != nil {
+		return nil, err
+	}
+	c := &classifier{rules: sections[""], styles: make(map[string]categoryStyle)}
+	for category, section := range sections {
+		if category == "" {
+			continue
+		}
+		var style categoryStyle
+		for key, value := range section {
+			switch key {
+			case "color":
+				style.color = value
+			case "shape":
+				style.shape = value
+			case "cluster":
+				style.cluster = value == "true"
+				if !style.cluster && value != "false" {
+					return nil, fmt.Errorf("%s: cluster of %s must be true or false, not %q", name, category, value)
+				}
+			default:
+				return nil, fmt.Errorf("%s: unknown setting %q for %s, expected color, shape or cluster", name, key, category)
+			}
+		}
+		c.styles[category] = style
+	}
+	return c, nil
+}
+
+// of returns the category of the most specific rule matching the package:
+// an exact import path first, then the longest prefix, then the first glob
+// in sorted order, or ""
+func (c *classifier) of(id string, stdlib bool) string {
+	if category, ok := c.rules[id]; ok {
+		return category
+	}
+	if stdlib {
+		if category, ok := c.rules["std"]; ok {
+			return category
+		}
+	}
+	var prefixes, globs []string
+	for pattern := range c.rules {
+		switch {
+		case isGlob(pattern):
+			globs = append(globs, pattern)
+		case pattern != "std" && strings.HasPrefix(id, pattern):
+			prefixes = append(prefixes, pattern)
+		}
+	}
+	if len(prefixes) > 0 {
+		sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+		return c.rules[prefixes[0]]
+	}
+	sort.Strings(globs)
+	for _, pattern := range globs {
+		if matchGlob(pattern, id) {
+			return c.rules[pattern]
+		}
+	}
+	return ""
+}
+
+// runClassifyHook runs the command with the nodes of the graph as a JSON
+// array on its stdin, and reads the categories it assigns from its output,
+// one "import path = category" line per package. Packages it does not name
+// keep their category.
+func runClassifyHook(command string, g *graph) (map[string]string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty -classify-exec command")
+	}
+	input, err := json.Marshal(g.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = logOutput
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run classify hook: %s", err)
+	}
+	categories := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(output))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		id, category, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("classify hook output line %d: expected import path = category", n)
+		}
+		categories[strings.TrimSpace(id)] = strings.TrimSpace(category)
+	}
+	return categories, s.Err()
+}
+
+// classify sets the category of every node, from the rules and then from
+// the hook, and draws it in the color and shape of its category. A category
+// drawn as a cluster takes in its packages unless -groups or a directive
+// already put them in a box.
+func (g *graph) classify(c *classifier, hook map[string]string) {
+	for _, n := range g.Nodes {
+		if n.Error != "" || n.Symbol {
+			continue
+		}
+		n.Category = c.of(n.ID, n.Stdlib)
+		if category, ok := hook[n.ID]; ok {
+			n.Category = category
+		}
+		if n.Category == "" {
+			continue
+		}
+		if n.Attrs == nil {
+			n.Attrs = attrs{}
+		}
+		n.Attrs.add(attrs{"tooltip": n.Category})
+		style := c.styles[n.Category]
+		if style.color != "" {
+			n.Color = style.color
+		}
+		if style.shape != "" {
+			n.Attrs["shape"] = style.shape
+		}
+		if style.cluster && n.Group == "" {
+			n.Group = n.Category
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// loadComplexity returns the summed cyclomatic complexity of every loaded
+// package, keyed by import path. If gocyclo is set, the numbers are taken
+// from that gocyclo output file instead of being computed from source.
+func loadComplexity(gocyclo string) (map[string]int, error) {
+	if gocyclo != "" {
+		return readGocyclo(gocyclo)
+	}
+
+	complexity := make(map[string]int)
+	for path, pkg := range pkgs {
+		// stdlib packages are not ours to simplify
+		if pkg.Goroot {
+			continue
+		}
+		c, err := packageComplexity(pkg.Dir, pkg.GoFiles)
+		if err != nil {
+			return nil, err
+		}
+		complexity[path] = c
+	}
+	return complexity, nil
+}
+
+func packageComplexity(dir string, files []string) (int, error) {
+	fset := token.NewFileSet()
+	total := 0
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %s", name, err)
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				total += funcComplexity(fn)
+			}
+		}
+	}
+	return total, nil
+}
+
+// funcComplexity counts decision points the same way gocyclo does
+func funcComplexity(fn *ast.FuncDecl) int {
+	c := 1
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			c++
+		case *ast.CaseClause:
+			if n.List != nil {
+				c++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				c++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				c++
+			}
+		}
+		return true
+	})
+	return c
+}
+
+// readGocyclo parses lines of the form
+// "<complexity> <package> <function> <file>:<row>:<column>"
+// and attributes each function to the loaded package owning its file.
+func readGocyclo(name string) (map[string]int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gocyclo output: %s", err)
+	}
+	defer f.Close()
+
+	byDir := make(map[string]string)
+	for path, pkg := range pkgs {
+		byDir[pathKey(pkg.Dir)] = path
+	}
+
+	complexity := make(map[string]int)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		c, err := strconv.Atoi(fields[0])
+		if err != nil || len(fields) < 4 {
+			logger.Warn("skipped malformed gocyclo line", "line", s.Text())
+			continue
+		}
+		file := strings.SplitN(fields[len(fields)-1], ":", 2)[0]
+		dir, err := filepath.Abs(filepath.Dir(file))
+		if err != nil {
+			continue
+		}
+		if path, ok := byDir[pathKey(dir)]; ok {
+			complexity[path] += c
+		} else {
+			logger.Debug("gocyclo function outside the graph", "file", file)
+		}
+	}
+	return complexity, s.Err()
+}
+
+// maxComplexity returns the highest complexity of all packages, at least 1
+func maxComplexity(complexity map[string]int) int {
+	max := 1
+	for _, v := range complexity {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// complexityAttrs scales the font of a node with its share of the maximum
+// complexity, so the complex packages stand out.
+func complexityAttrs(pkgName string, complexity map[string]int, max int) attrs {
+	c, ok := complexity[pkgName]
+	if !ok {
+		return nil
+	}
+	return attrs{
+		"label":    fmt.Sprintf("(complexity %d)", c),
+		"fontsize": strconv.Itoa(14 + 22*c/max),
+		"tooltip":  fmt.Sprintf("cyclomatic complexity %d", c),
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultConfigFile is read from the working directory if -config is not given
+const defaultConfigFile = ".godepgraph"
+
+// configure fills in every flag that was not given on the command line,
+// first from its GODEPGRAPH_* environment variable, then from the config
+// file, where the section of the view selected by -view overrides the rest.
+// Flags thus take precedence over the environment, and the environment over
+// the config file.
+func configure() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	if !given["config"] {
+		if v, ok := os.LookupEnv(envName("config")); ok {
+			*configFile = v
+		}
+	}
+	name := *configFile
+	if name == "" {
+		if _, err := os.Stat(defaultConfigFile); err == nil {
+			name = defaultConfigFile
+		}
+	}
+	sections := map[string]map[string]string{"": {}}
+	var err error
+	if name != "" {
+		if sections, err = readSections(name); err != nil {
+			return err
+		}
+	}
+	for section, settings := range sections {
+		for key := range settings {
+			if flag.Lookup(key) == nil || key == "view" && section != "" {
+				return fmt.Errorf("unknown setting %q in %s", key, name)
+			}
+		}
+	}
+
+	// the settings of a view override the ones outside of the sections
+	config := sections[""]
+	if !given["view"] {
+		if v, ok := os.LookupEnv(envName("view")); ok {
+			*viewName = v
+		} else if v, ok := config["view"]; ok {
+			*viewName = v
+		}
+	}
+	if *viewName != "" {
+		view, ok := sections[*viewName]
+		if !ok {
+			return fmt.Errorf("unknown view %q, the config file defines %s", *viewName, viewNames(sections))
+		}
+		for key, v := range view {
+			config[key] = v
+		}
+	}
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || f.Name == "config" || f.Name == "view" || err != nil {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if e := f.Value.Set(v); e != nil {
+				err = fmt.Errorf("invalid value %q for %s: %s", v, envName(f.Name), e)
+			}
+			return
+		}
+		if v, ok := config[f.Name]; ok {
+			if e := f.Value.Set(v); e != nil {
+				err = fmt.Errorf("invalid value %q for %s in config file: %s", v, f.Name, e)
+			}
+		}
+	})
+	return err
+}
+
+// viewNames lists the views defined by the sections of a config file
+func viewNames(sections map[string]map[string]string) string {
+	var names []string
+	for section := range sections {
+		if section != "" {
+			names = append(names, section)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// envName returns the environment variable mirroring a flag, e.g.
+// GODEPGRAPH_MAX_DEPTH for -max-depth
+func envName(flagName string) string {
+	return "GODEPGRAPH_" + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// readConfig reads a config file of "name = value" lines, one per flag, with
+// the flag names as on the command line. Blank lines and lines starting with
+// # are skipped.
+func readConfig(name string) (map[string]string, error) {
+	sections, err := readSections(name)
+	if err != nil {
+		return nil, err
+	}
+	for section := range sections {
+		if section != "" {
+			return nil, fmt.Errorf("%s: unexpected section [%s]", name, section)
+		}
+	}
+	return sections[""], nil
+}
+
+// readSections reads a config file like readConfig, where lines like
+// "[name]" start a section of their own. The lines before the first section
+// are returned as the section "".
+func readSections(name string) (map[string]map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %s", err)
+	}
+	defer f.Close()
+
+	config := make(map[string]string)
+	sections := map[string]map[string]string{"": config}
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" || sections[section] != nil {
+				return nil, fmt.Errorf("%s:%d: empty or repeated section", name, n)
+			}
+			config = make(map[string]string)
+			sections[section] = config
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected name = value", name, n)
+		}
+		config[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return sections, s.Err()
+}
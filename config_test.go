@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFilterSettingPrecedence checks that a filter given on the command line
+// wins over its environment variable, which wins over the view, which wins
+// over the top of the config file, and that patterns set there work the
+// same as on the command line
+func TestFilterSettingPrecedence(t *testing.T) {
+	resetFilters(t)
+	name := filepath.Join(t.TempDir(), "godepgraph.conf")
+	config := `i = example.com/config/**
+p = example.com/config-prefix
+n = example.com/config/keep
+s = true
+
+[core]
+n = example.com/view/**
+`
+	if err := os.WriteFile(name, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	savedConfig, savedView, savedStdlib := *configFile, *viewName, *ignoreStdlib
+	defer func() { *configFile, *viewName, *ignoreStdlib = savedConfig, savedView, savedStdlib }()
+	*configFile, *viewName = name, "core"
+	t.Setenv(envName("config"), "")
+	os.Unsetenv(envName("config"))
+	t.Setenv(envName("view"), "core")
+	t.Setenv(envName("i"), "example.com/env/**")
+	if err := flag.Set("p", "example.com/flag/*/mocks"); err != nil {
+		t.Fatal(err)
+	}
+	if err := configure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := setupFilters(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		setting, got, want string
+	}{
+		{"-p from the flag over the config", *ignorePrefixes, "example.com/flag/*/mocks"},
+		{"-i from the environment over the config", *ignorePackages, "example.com/env/**"},
+		{"-n from the view over the config", *includePackages, "example.com/view/**"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.setting, tt.got, tt.want)
+		}
+	}
+	if !*ignoreStdlib {
+		t.Errorf("-s from the config file not set")
+	}
+
+	reasons := []struct {
+		pkg, want string
+	}{
+		{"example.com/env/a/b", "pattern example.com/env/** ignored by -i"},
+		{"example.com/config/a", ""},
+		{"example.com/flag/x/mocks", "pattern example.com/flag/*/mocks ignored by -p"},
+		{"example.com/config-prefix/a", ""},
+		{"example.com/view/a", ""},
+	}
+	for _, r := range reasons {
+		if got := ignoreReason(&build.Package{ImportPath: r.pkg}); got != r.want {
+			t.Errorf("ignoreReason(%s) = %q, want %q", r.pkg, got, r.want)
+		}
+	}
+}
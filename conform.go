@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// archModel is the intended architecture: the components and the imports
+// allowed between them
+type archModel struct {
+	components []string
+	allowed    map[[2]string]bool
+}
+
+// loadModel reads the intended architecture from a hand-written DOT file.
+// Nodes name components, edges the imports allowed between them; attributes
+// and subgraphs only serve the drawing and are skipped.
+func loadModel(name string) (*archModel, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model: %s", err)
+	}
+	if err := validateDot(string(data)); err != nil {
+		return nil, fmt.Errorf("invalid model %s: %s", name, err)
+	}
+	tokens, err := lexDot(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid model %s: %s", name, err)
+	}
+
+	m := &archModel{allowed: make(map[[2]string]bool)}
+	seen := make(map[string]bool)
+	component := func(t dotToken) string {
+		id := t.text
+		if unquoted, err := strconv.Unquote(id); err == nil {
+			id = unquoted
+		}
+		if !seen[id] {
+			seen[id] = true
+			m.components = append(m.components, id)
+		}
+		return id
+	}
+	isID := func(i int) bool {
+		return i < len(tokens) && tokens[i].id && !isDotKeyword(tokens[i].text)
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.text == "[":
+			for i < len(tokens) && tokens[i].text != "]" {
+				i++
+			}
+		case isDotKeyword(t.text):
+			// the names of graphs and subgraphs are not components
+			if isID(i+1) && strings.ToLower(t.text) != "node" && strings.ToLower(t.text) != "edge" {
+				i++
+			}
+		case isID(i) && i+1 < len(tokens) && tokens[i+1].text == "=":
+			i += 2
+		case isID(i):
+			from := component(t)
+			for i+2 < len(tokens) && tokens[i+1].text == "->" && isID(i+2) {
+				to := component(tokens[i+2])
+				m.allowed[[2]string{from, to}] = true
+				from = to
+				i += 2
+			}
+		}
+	}
+	if len(m.components) == 0 {
+		return nil, fmt.Errorf("model %s has no components", name)
+	}
+	return m, nil
+}
+
+// componentOf returns the component of the model a node belongs to: its
+// group if the model names it, or else the longest component that is the
+// import path of the package or a parent of it. The component std stands
+// for the standard library. It returns "" for packages outside the model.
+func (m *archModel) componentOf(n *node) string {
+	names := stringSet(m.components)
+	if n.Group != "" && names[n.Group] {
+		return n.Group
+	}
+	if n.Stdlib && names["std"] {
+		return "std"
+	}
+	best := ""
+	for _, c := range m.components {
+		if (n.ID == c || strings.HasPrefix(n.ID, c+"/")) && len(c) > len(best) {
+			best = c
+		}
+	}
+	return best
+}
+
+// conformance compares the imports between the components of the graph to
+// the model
+type conformance struct {
+	allowed, illegal map[[2]string][]*edge
+	missing          [][2]string
+	outside          int
+}
+
+func (g *graph) conformance(m *archModel) *conformance {
+	c := &conformance{allowed: make(map[[2]string][]*edge), illegal: make(map[[2]string][]*edge)}
+	componentOf := make(map[string]string)
+	for _, n := range g.Nodes {
+		if n.Ghost || n.Error != "" {
+			continue
+		}
+		if componentOf[n.ID] = m.componentOf(n); componentOf[n.ID] == "" {
+			c.outside++
+		}
+	}
+	for _, e := range g.Edges {
+		from, to := componentOf[e.From], componentOf[e.To]
+		if from == "" || to == "" || from == to {
+			continue
+		}
+		pair := [2]string{from, to}
+		if m.allowed[pair] {
+			c.allowed[pair] = append(c.allowed[pair], e)
+		} else {
+			c.illegal[pair] = append(c.illegal[pair], e)
+		}
+	}
+	for pair := range m.allowed {
+		if c.allowed[pair] == nil {
+			c.missing = append(c.missing, pair)
+		}
+	}
+	sortPairs(c.missing)
+	return c
+}
+
+func sortPairs(pairs [][2]string) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+}
+
+func sortedPairs(edges map[[2]string][]*edge) [][2]string {
+	var pairs [][2]string
+	for pair := range edges {
+		pairs = append(pairs, pair)
+	}
+	sortPairs(pairs)
+	return pairs
+}
+
+// runConform prints which imports between the components of the graph the
+// model allows, which allowed ones do not exist and which are illegal, and
+// with -model-diff draws the comparison. It reports whether illegal imports
+// were found.
+func runConform(g *graph) (bool, error) {
+	if *modelFile == "" {
+		return false, fmt.Errorf("conform needs -model")
+	}
+	m, err := loadModel(*modelFile)
+	if err != nil {
+		return false, err
+	}
+	c := g.conformance(m)
+
+	imports := func(n int) string {
+		return fmt.Sprintf("%d %s", n, choose(n == 1, "import", "imports"))
+	}
+	fmt.Printf("Conformance with %s: %d allowed, %d missing, %d illegal\n",
+		*modelFile, len(c.allowed), len(c.missing), len(c.illegal))
+	if len(c.allowed) > 0 {
+		fmt.Println("\nallowed:")
+		for _, pair := range sortedPairs(c.allowed) {
+			fmt.Printf("  %s -> %s (%s)\n", pair[0], pair[1], imports(len(c.allowed[pair])))
+		}
+	}
+	if len(c.missing) > 0 {
+		fmt.Println("\nmissing:")
+		for _, pair := range c.missing {
+			fmt.Printf("  %s -> %s\n", pair[0], pair[1])
+		}
+	}
+	if len(c.illegal) > 0 {
+		fmt.Println("\nillegal:")
+		for _, pair := range sortedPairs(c.illegal) {
+			fmt.Printf("  %s -> %s (%s)\n", pair[0], pair[1], imports(len(c.illegal[pair])))
+			for _, e := range c.illegal[pair] {
+				fmt.Printf("    %s -> %s\n", e.From, e.To)
+			}
+		}
+	}
+	if c.outside > 0 {
+		fmt.Printf("\n%d %s outside the model\n", c.outside, choose(c.outside == 1, "package", "packages"))
+	}
+
+	if *modelDiff != "" {
+		if err := writeModelDiff(*modelDiff, m, c); err != nil {
+			return false, err
+		}
+	}
+	return len(c.illegal) > 0, nil
+}
+
+// writeModelDiff draws the components with the allowed imports in black,
+// the missing ones dashed grey and the illegal ones red, in the format
+// selected by -render
+func writeModelDiff(name string, m *archModel, c *conformance) error {
+	d := &graph{}
+	for _, id := range m.components {
+		d.Nodes = append(d.Nodes, &node{ID: id, Color: "white", Attrs: attrs{"shape": "box", "style": "filled,rounded", "color": "black"}})
+	}
+	for _, pair := range sortedPairs(c.allowed) {
+		d.Edges = append(d.Edges, &edge{From: pair[0], To: pair[1], Attrs: attrs{"label": fmt.Sprint(len(c.allowed[pair]))}})
+	}
+	for _, pair := range c.missing {
+		d.Edges = append(d.Edges, &edge{From: pair[0], To: pair[1], Attrs: attrs{"style": "dashed", "color": "grey", "fontcolor": "grey", "label": "missing"}})
+	}
+	for _, pair := range sortedPairs(c.illegal) {
+		d.Edges = append(d.Edges, &edge{From: pair[0], To: pair[1], Attrs: attrs{"color": "red", "fontcolor": "red", "label": fmt.Sprintf("%d illegal", len(c.illegal[pair]))}})
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %s", name, err)
+	}
+	if err := renderDot(f, d, *renderFormat); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %s", name, err)
+	}
+	return nil
+}
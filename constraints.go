@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"sort"
+	"strings"
+)
+
+var (
+	knownOS   = "aix android darwin dragonfly freebsd hurd illumos ios js linux netbsd openbsd plan9 solaris wasip1 windows zos"
+	knownArch = "386 amd64 arm arm64 loong64 mips mips64 mips64le mipsle ppc64 ppc64le riscv64 s390x wasm"
+
+	// constraintSets holds the build contexts given by -constraints
+	constraintSets []*constraintSet
+
+	// conditional maps packages to their imports and the names of the
+	// constraint sets that make them, with -constraints
+	conditional = make(map[string]map[string][]string)
+)
+
+// constraintSet is a build context to resolve packages under, named like it
+// was given on the command line
+type constraintSet struct {
+	name string
+	ctx  build.Context
+}
+
+// parseConstraints parses a comma-separated list of constraint sets. Each set
+// joins a GOOS, a GOARCH and build tags with "+", as in linux+arm64+purego,
+// where GOOS and GOARCH can also be given as linux/arm64; whatever is left
+// out is taken from the default build context.
+func parseConstraints(list string) ([]*constraintSet, error) {
+	var sets []*constraintSet
+	for _, name := range sanitizeCSV(list) {
+		set := &constraintSet{name: name, ctx: build.Default}
+		set.ctx.BuildTags = nil
+		for _, term := range strings.Split(name, "+") {
+			if goos, goarch, ok := strings.Cut(term, "/"); ok && isKnown(knownOS, goos) && isKnown(knownArch, goarch) {
+				set.ctx.GOOS, set.ctx.GOARCH = goos, goarch
+				continue
+			}
+			switch {
+			case term == "":
+				return nil, fmt.Errorf("empty term in constraint set %q", name)
+			case isKnown(knownOS, term):
+				set.ctx.GOOS = term
+			case isKnown(knownArch, term):
+				set.ctx.GOARCH = term
+			case term == "cgo":
+				set.ctx.CgoEnabled = true
+			default:
+				set.ctx.BuildTags = append(set.ctx.BuildTags, term)
+			}
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// parsePlatforms parses the comma-separated GOOS/GOARCH pairs of -platforms
+func parsePlatforms(list string) ([]*constraintSet, error) {
+	for _, name := range sanitizeCSV(list) {
+		goos, goarch, ok := strings.Cut(name, "/")
+		if !ok || !isKnown(knownOS, goos) || !isKnown(knownArch, goarch) {
+			return nil, fmt.Errorf("invalid platform %q, expected GOOS/GOARCH like linux/amd64", name)
+		}
+	}
+	return parseConstraints(list)
+}
+
+// usePlatforms resolves the packages under the first of the -platforms sets
+// instead of the platform godepgraph runs on, so that the graph is made of
+// the listed platforms alone and the own platform only takes part if it is
+// one of them
+func usePlatforms(sets []*constraintSet) {
+	constraintSets = sets
+	build.Default.GOOS, build.Default.GOARCH = sets[0].ctx.GOOS, sets[0].ctx.GOARCH
+}
+
+// defaultSetName names the default build context among the constraint sets:
+// default with -constraints, and its GOOS/GOARCH with -platforms, so that
+// it is the same as the set of the first platform
+func defaultSetName() string {
+	if *platformsFlag != "" {
+		return build.Default.GOOS + "/" + build.Default.GOARCH
+	}
+	return "default"
+}
+
+// constraintSetNames returns the names of the default build context and
+// the constraint sets, without duplicates
+func constraintSetNames() []string {
+	names := []string{defaultSetName()}
+	for _, set := range constraintSets {
+		if set.name != names[0] {
+			names = append(names, set.name)
+		}
+	}
+	return names
+}
+
+func isKnown(list, term string) bool {
+	for _, v := range strings.Fields(list) {
+		if v == term {
+			return true
+		}
+	}
+	return false
+}
+
+// importPackage imports a package with the default build context. A package
+// that has no Go files there, like syscall/js, is looked for under the
+// constraint sets as well.
+func importPackage(pkgName, root string) (*build.Package, error) {
+	pkg, err := build.Import(pkgName, root, 0)
+	if _, ok := err.(*build.NoGoError); ok {
+		for _, set := range constraintSets {
+			if p, err := set.ctx.Import(pkgName, root, 0); err == nil {
+				markGoroot(p)
+				return p, nil
+			}
+		}
+	}
+	if pkg != nil {
+		markGoroot(pkg)
+	}
+	return pkg, err
+}
+
+// loadConditional resolves pkg under every constraint set and records which
+// of them make each of its imports
+func loadConditional(pkg *build.Package) {
+	if len(constraintSets) == 0 || pkg.Goroot {
+		return
+	}
+	byImport := make(map[string][]string)
+	for _, imp := range pkg.Imports {
+		byImport[imp] = append(byImport[imp], defaultSetName())
+	}
+	for _, set := range constraintSets {
+		if set.name == defaultSetName() {
+			continue
+		}
+		p, err := set.ctx.ImportDir(pkg.Dir, 0)
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); !ok {
+				logger.Warn("failed to resolve package under constraints", "pkg", pkg.ImportPath, "constraints", set.name, "err", err)
+			}
+			continue
+		}
+		for _, imp := range p.Imports {
+			byImport[imp] = append(byImport[imp], set.name)
+		}
+	}
+	conditional[pkg.ImportPath] = byImport
+}
+
+// conditionalImports returns the imports of pkg that only some constraint
+// sets make, and the default build context does not
+func conditionalImports(pkg *build.Package) []string {
+	var imps []string
+	for imp, sets := range conditional[pkg.ImportPath] {
+		if sets[0] != defaultSetName() {
+			imps = append(imps, imp)
+		}
+	}
+	sort.Strings(imps)
+	return imps
+}
+
+// importConstraints returns the names of the constraint sets making the
+// import, or nil if all of them and the default build context make it
+func importConstraints(pkgName, imp string) []string {
+	sets, ok := conditional[pkgName][imp]
+	if !ok || len(sets) == len(constraintSetNames()) {
+		return nil
+	}
+	return sets
+}
+
+// markConstraints outlines the nodes that are only reached from the roots
+// under some of the constraint sets, following the edges each of them makes,
+// and labels them with those sets
+func (g *graph) markConstraints() {
+	names := constraintSetNames()
+	reached := make(map[string][]string)
+	for _, name := range names {
+		out := make(map[string][]string)
+		for _, e := range g.Edges {
+			if e.Constraints == nil || stringSet(e.Constraints)[name] {
+				out[e.From] = append(out[e.From], e.To)
+			}
+		}
+		seen := make(map[string]bool)
+		queue := append([]string(nil), g.Roots...)
+		for _, root := range g.Roots {
+			seen[root] = true
+		}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			reached[v] = append(reached[v], name)
+			for _, w := range out[v] {
+				if !seen[w] {
+					seen[w] = true
+					queue = append(queue, w)
+				}
+			}
+		}
+	}
+	for _, n := range g.Nodes {
+		sets, ok := reached[n.ID]
+		if !ok || len(sets) == len(names) {
+			continue
+		}
+		n.Constraints = sets
+		n.Attrs.add(constraintNodeAttrs(sets))
+	}
+}
+
+func constraintNodeAttrs(sets []string) attrs {
+	return attrs{
+		"color":    "darkviolet",
+		"penwidth": "2",
+		"label":    "(" + strings.Join(sets, ", ") + ")",
+		"tooltip":  "only with " + strings.Join(sets, ", "),
+	}
+}
+
+func constraintEdgeAttrs(sets []string) attrs {
+	return attrs{
+		"color":     "darkviolet",
+		"fontcolor": "darkviolet",
+		"label":     strings.Join(sets, ", "),
+		"tooltip":   "only with " + strings.Join(sets, ", "),
+	}
+}
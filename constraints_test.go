@@ -0,0 +1,36 @@
+package main
+
+import (
+	"go/build"
+	"strings"
+	"testing"
+)
+
+func TestPlatformsLeaveOutHost(t *testing.T) {
+	saved, sets, flag := build.Default, constraintSets, *platformsFlag
+	t.Cleanup(func() { build.Default, constraintSets, *platformsFlag = saved, sets, flag })
+
+	host := build.Default.GOOS + "/" + build.Default.GOARCH
+	tests := []struct {
+		platforms string
+		want      string
+	}{
+		{"windows/amd64,darwin/arm64", "windows/amd64,darwin/arm64"},
+		{"plan9/386," + host, "plan9/386," + host},
+		{host + ",plan9/386", host + ",plan9/386"},
+		{"", "default"},
+	}
+	for _, tt := range tests {
+		build.Default, constraintSets, *platformsFlag = saved, nil, tt.platforms
+		if tt.platforms != "" {
+			parsed, err := parsePlatforms(tt.platforms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			usePlatforms(parsed)
+		}
+		if got := strings.Join(constraintSetNames(), ","); got != tt.want {
+			t.Errorf("-platforms %q scans under %s, want %s", tt.platforms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contractChains replaces every chain of packages with exactly one importer
+// and one import by a single edge from the importer of the chain to where it
+// leads, labelled with the number of packages left out. Roots are kept.
+func (g *graph) contractChains() {
+	key := func(namespace, id string) string {
+		return g.namespaceOf(namespace) + "\x00" + id
+	}
+	in := make(map[string]int)
+	out := make(map[string][]*edge)
+	for _, e := range g.Edges {
+		in[key(e.Namespace, e.To)]++
+		out[key(e.Namespace, e.From)] = append(out[key(e.Namespace, e.From)], e)
+	}
+	roots := make(map[string]bool)
+	for _, root := range g.Roots {
+		roots[root] = true
+	}
+	passThrough := make(map[string]bool)
+	for _, n := range g.Nodes {
+		k := key(n.Namespace, n.ID)
+		if !roots[n.ID] && in[k] == 1 && len(out[k]) == 1 {
+			passThrough[k] = true
+		}
+	}
+
+	contracted := make(map[string]bool)
+	var edges []*edge
+	for _, e := range g.Edges {
+		from, to := key(e.Namespace, e.From), key(e.Namespace, e.To)
+		if passThrough[from] {
+			continue
+		}
+		if !passThrough[to] {
+			edges = append(edges, e)
+			continue
+		}
+		var chain []string
+		next := e
+		for passThrough[to] && !contracted[to] {
+			contracted[to] = true
+			chain = append(chain, next.To)
+			next = out[to][0]
+			to = key(next.Namespace, next.To)
+		}
+		if len(chain) == 0 {
+			// the chain closes a cycle consumed from elsewhere
+			edges = append(edges, e)
+			continue
+		}
+		edges = append(edges, &edge{
+			Namespace: e.Namespace,
+			From:      e.From,
+			To:        next.To,
+			Attrs: attrs{
+				"label":   fmt.Sprintf("via %d", len(chain)),
+				"style":   "bold",
+				"tooltip": strings.Join(chain, " -> "),
+			},
+		})
+	}
+
+	var nodes []*node
+	for _, n := range g.Nodes {
+		if !contracted[key(n.Namespace, n.ID)] {
+			nodes = append(nodes, n)
+		}
+	}
+	// pass-through packages not reached from outside a chain keep their edges
+	for _, e := range g.Edges {
+		if from := key(e.Namespace, e.From); passThrough[from] && !contracted[from] {
+			edges = append(edges, e)
+		}
+	}
+	g.Nodes, g.Edges = nodes, edges
+}
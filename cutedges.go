@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cutRule suppresses the edges from packages matching from to packages
+// matching to. Both are import paths or glob patterns.
+type cutRule struct {
+	from, to string
+}
+
+// parseCutRules parses the comma-separated from->to rules of -cut-edges
+func parseCutRules(list string) ([]cutRule, error) {
+	var rules []cutRule
+	for _, rule := range strings.Split(list, ",") {
+		from, to, ok := strings.Cut(rule, "->")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid edge rule %q, expected from->to", strings.TrimSpace(rule))
+		}
+		rules = append(rules, cutRule{from: from, to: to})
+	}
+	return rules, nil
+}
+
+func (r cutRule) matches(e *edge) bool {
+	match := func(pattern, id string) bool {
+		return pattern == id || isGlob(pattern) && matchGlob(pattern, id)
+	}
+	return match(r.from, e.From) && match(r.to, e.To)
+}
+
+// cutEdges drops the edges matching any of the rules but keeps all nodes,
+// noting on every importer how many of its imports were cut
+func (g *graph) cutEdges(rules []cutRule) {
+	cut := make(map[string]int)
+	var edges []*edge
+	for _, e := range g.Edges {
+		matched := false
+		for _, r := range rules {
+			matched = matched || r.matches(e)
+		}
+		if matched {
+			cut[e.From]++
+		} else {
+			edges = append(edges, e)
+		}
+	}
+	if len(edges) == len(g.Edges) {
+		logger.Warn("no edges matched -cut-edges")
+		return
+	}
+	for _, n := range g.Nodes {
+		if cut[n.ID] > 0 {
+			if n.Attrs == nil {
+				n.Attrs = attrs{}
+			}
+			n.Attrs.add(attrs{"label": fmt.Sprintf("(%d %s cut)", cut[n.ID], choose(cut[n.ID] == 1, "import", "imports"))})
+		}
+	}
+	logger.Info("cut edges", "cut", len(g.Edges)-len(edges))
+	g.Edges = edges
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// cycle is an import cycle as written by -cycles-json: the packages in
+// import order, and the edge from the last one back to the first
+type cycle struct {
+	Packages []string `json:"packages"`
+	Closing  struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"closing"`
+}
+
+// orderedCycles returns one shortest cycle through the first package of
+// every strongly connected component of the graph
+func (g *graph) orderedCycles() []cycle {
+	next := g.imports()
+	var list []cycle
+	for _, scc := range g.cycles() {
+		members := stringSet(scc)
+		start := scc[0]
+		for _, id := range scc {
+			if id < start {
+				start = id
+			}
+		}
+
+		// breadth-first search within the component back to the start
+		parent := map[string]string{start: ""}
+		queue := []string{start}
+		var last string
+		for len(queue) > 0 && last == "" {
+			v := queue[0]
+			queue = queue[1:]
+			for _, w := range next(v) {
+				if w == start {
+					last = v
+					break
+				}
+				if _, ok := parent[w]; !ok && members[w] {
+					parent[w] = v
+					queue = append(queue, w)
+				}
+			}
+		}
+		var path []string
+		for p := last; p != ""; p = parent[p] {
+			path = append([]string{p}, path...)
+		}
+		c := cycle{Packages: path}
+		c.Closing.From, c.Closing.To = last, start
+		list = append(list, c)
+	}
+	return list
+}
+
+// writeCycles writes the import cycles of the graph to a JSON file, or to
+// stdout for -
+func writeCycles(name string, g *graph) error {
+	cycles := g.orderedCycles()
+	if cycles == nil {
+		cycles = []cycle{}
+	}
+	data, err := json.MarshalIndent(cycles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cycles: %s", err)
+	}
+	data = append(data, '\n')
+	if name == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cycles: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/build"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// daemonFlags are the flags a query to the daemon can set: the views and
+// settings of the repl and the budgets of check that neither name a file nor
+// run a command, as anyone who can reach the daemon can send queries
+var daemonFlags = []string{
+	"cut-edges", "fan-in", "set", "reachable-to", "focus-neighbours", "sample", "contract-chains", "collapse-groups",
+	"format", "render", "anonymize", "validate", "imports-all", "remove", "candidates",
+	"max-external-modules", "max-depth", "max-cycles",
+}
+
+// daemonQueryFlags are the flags the daemon applies to the graph for every
+// query rather than passing them on to the scans. The ones a query cannot
+// set keep the values the daemon was started with.
+var daemonQueryFlags = append(append([]string(nil), replFlags...), "max-external-modules", "max-depth", "max-cycles", "allowed", "exemptions")
+
+// daemonOnlyFlags configure the daemon or its clients and are not passed on
+// to the scans
+var daemonOnlyFlags = []string{"listen", "poll", "daemon"}
+
+// clientFlags only concern the run of a client and may differ from the
+// daemon's
+var clientFlags = []string{"q", "v", "vv", "log-file", "log-json"}
+
+const (
+	// exitStatusHeader carries the exit status of a query back to the client
+	exitStatusHeader = "Godepgraph-Exit-Status"
+	// scanHeader carries the scan settings a client was given, as query
+	// parameters, for the daemon to compare with its own
+	scanHeader = "Godepgraph-Scan"
+	// packageHeader and dirHeader carry the package a client was given and
+	// the directory it resolves from
+	packageHeader = "Godepgraph-Package"
+	dirHeader     = "Godepgraph-Dir"
+)
+
+// daemon keeps the scanned graph of a workspace in memory and rescans it
+// when its files change
+type daemon struct {
+	pkg  string
+	root string // the import path of pkg
+	top  string // the directory watched for changes
+
+	// mu guards the graph, and serializes the queries, as they change
+	// the flags and stdout
+	mu      sync.Mutex
+	whole   []byte // the graph as JSON, without views, copied for every query
+	stamp   uint64 // fingerprint of the files the graph was scanned from
+	scanned time.Time
+}
+
+// runDaemon scans the package and answers queries for the graph over HTTP
+// on -listen until it is stopped. The work tree of the package, its module
+// or otherwise the current directory, is checked for changed, added or
+// removed files every -poll, and rescanned in the background if any did.
+func runDaemon(pkg, cwd string) error {
+	d := &daemon{pkg: pkg, top: cwd}
+	root, err := resolveRoot(pkg, cwd)
+	if err != nil {
+		return err
+	}
+	d.root = root
+	dir := pkg
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	if name := goModFile(dir); name != "" {
+		d.top = filepath.Dir(name)
+	}
+	stamp, err := fingerprint(d.top)
+	if err != nil {
+		return err
+	}
+	if err := d.rescan(stamp); err != nil {
+		return err
+	}
+	go d.watch()
+
+	http.HandleFunc("/", d.serve)
+	logger.Info("daemon listening", "addr", *listenAddr, "pkg", pkg, "dir", d.top)
+	return http.ListenAndServe(*listenAddr, nil)
+}
+
+// watch rescans the graph whenever the fingerprint of the work tree
+// changes. Until a rescan finishes, queries are answered from the graph
+// before it.
+func (d *daemon) watch() {
+	for range time.Tick(*pollInterval) {
+		stamp, err := fingerprint(d.top)
+		if err != nil {
+			logger.Warn("failed to check for changes", "dir", d.top, "err", err)
+			continue
+		}
+		d.mu.Lock()
+		changed := stamp != d.stamp
+		d.mu.Unlock()
+		if !changed {
+			continue
+		}
+		logger.Info("files changed, rescanning", "dir", d.top)
+		if err := d.rescan(stamp); err != nil {
+			logger.Error("failed to rescan", "err", err)
+		}
+	}
+}
+
+// rescan scans the package in a separate run of godepgraph, with the views
+// and output settings at their defaults so that queries can apply their
+// own, and keeps the JSON graph it prints
+func (d *daemon) rescan(stamp uint64) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find godepgraph: %s", err)
+	}
+	skip := stringSet(append(append([]string(nil), daemonQueryFlags...), daemonOnlyFlags...))
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if !skip[f.Name] {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	for _, name := range daemonQueryFlags {
+		args = append(args, "-"+name+"="+flag.Lookup(name).DefValue)
+	}
+	if *cyclesJSON == "-" {
+		// stdout is the graph. A file of -cycles-json is written by every
+		// scan, so it follows the graph.
+		args = append(args, "-cycles-json=")
+	}
+	args = append(args, "-format=json", d.pkg)
+
+	start := time.Now()
+	cmd := exec.Command(exe, args...)
+	cmd.Stderr = logOutput
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && (exitErr.ExitCode() == exitCycles || exitErr.ExitCode() == exitUnresolved) {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %s", d.pkg, err)
+	}
+	g := new(graph)
+	if err := json.Unmarshal(output, g); err != nil {
+		return fmt.Errorf("failed to read scanned graph: %s", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.whole, d.stamp, d.scanned = output, stamp, time.Now()
+	unresolved = make(map[string]error)
+	for _, n := range g.Nodes {
+		if n.Error != "" {
+			unresolved[n.ID] = errors.New(n.Error)
+		}
+	}
+	logger.Info("scanned", "pkg", d.pkg, "packages", len(g.Nodes), "took", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// serve answers a query: the path names the subcommand, or graph for the
+// graph itself, and the parameters set flags like -name=value would. The
+// output is the one of the subcommand, the exit status is in a header.
+func (d *daemon) serve(w http.ResponseWriter, r *http.Request) {
+	if !allowedHost(r.Host) {
+		// guards against web pages sending queries through DNS rebinding
+		http.Error(w, fmt.Sprintf("queries for host %q are not answered, only for the -listen address or localhost", r.Host), http.StatusForbidden)
+		return
+	}
+	command := strings.Trim(r.URL.Path, "/")
+	if command != "graph" && !replCommands[command] {
+		http.Error(w, fmt.Sprintf("unknown query %q, expected graph or one of the subcommands the repl runs", command), http.StatusNotFound)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.sameScan(r.Header); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	allowed := stringSet(daemonFlags)
+	// the scan wrote the cycles, a query does not
+	saved := map[string]string{"cycles-json": *cyclesJSON}
+	*cyclesJSON = ""
+	defer func() {
+		for name, value := range saved {
+			flag.Set(name, value)
+		}
+	}()
+	for name, values := range r.URL.Query() {
+		if !allowed[name] {
+			http.Error(w, fmt.Sprintf("-%s cannot be set in a query, only when starting the daemon. queries can set %s", name, strings.Join(daemonFlags, ", ")), http.StatusBadRequest)
+			return
+		}
+		saved[name] = flag.Lookup(name).Value.String()
+		if err := flag.Set(name, values[len(values)-1]); err != nil {
+			http.Error(w, fmt.Sprintf("invalid value %q for -%s: %s", values[len(values)-1], name, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	g := new(graph)
+	err := json.Unmarshal(d.whole, g)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to copy graph: %s", err), http.StatusInternalServerError)
+		return
+	}
+	if err := g.applyViews(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var output bytes.Buffer
+	var failed bool
+	if command == "graph" {
+		err = writeGraph(&output, g)
+	} else {
+		failed, err = captureStdout(&output, func() (bool, error) {
+			return runGraphCommand(command, g)
+		})
+	}
+	var cyc bool
+	if err == nil {
+		cyc, err = cyclic(command, g.cycles())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set(exitStatusHeader, strconv.Itoa(exitStatus(failed, cyc)))
+	w.Header().Set("Last-Modified", d.scanned.UTC().Format(http.TimeFormat))
+	output.WriteTo(w)
+}
+
+// sameScan checks that the scan settings and the package a client was given
+// are the ones of the daemon, as its graph would not be the one the client
+// asked for otherwise
+func (d *daemon) sameScan(h http.Header) error {
+	settings, err := url.ParseQuery(h.Get(scanHeader))
+	if err != nil {
+		return fmt.Errorf("invalid %s header: %s", scanHeader, err)
+	}
+	var differ []string
+	for name := range settings {
+		f := flag.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("unknown flag -%s", name)
+		}
+		if value := settings.Get(name); value != f.Value.String() {
+			differ = append(differ, fmt.Sprintf("-%s is %q there, not %q", name, f.Value.String(), value))
+		}
+	}
+	if pkg := h.Get(packageHeader); pkg != "" {
+		root, err := resolveRoot(pkg, h.Get(dirHeader))
+		if err != nil {
+			return err
+		}
+		if root != d.root {
+			differ = append(differ, fmt.Sprintf("the package is %s there, not %s", d.root, root))
+		}
+	}
+	if len(differ) > 0 {
+		sort.Strings(differ)
+		return fmt.Errorf("the daemon scans with other settings: %s. Leave them out of the query, or restart the daemon with them", strings.Join(differ, ", "))
+	}
+	return nil
+}
+
+// resolveRoot returns the import path of a package given on the command
+// line, resolved from dir
+func resolveRoot(arg, dir string) (string, error) {
+	pkg, err := importPackage(importArg(arg), dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %s", arg, err)
+	}
+	if build.IsLocalImport(pkg.ImportPath) {
+		return moduleImportPath(pkg.Dir, pkg.ImportPath), nil
+	}
+	return pkg.ImportPath, nil
+}
+
+// allowedHost reports whether the Host header of a query names the -listen
+// address or the loopback interface
+func allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	if listen, _, err := net.SplitHostPort(*listenAddr); err == nil && host == listen {
+		return true
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// captureStdout runs f with stdout going to w, for the subcommands that
+// print their results
+func captureStdout(w io.Writer, f func() (bool, error)) (bool, error) {
+	r, pipe, err := os.Pipe()
+	if err != nil {
+		return false, err
+	}
+	done := make(chan error)
+	go func() {
+		_, err := io.Copy(w, r)
+		r.Close()
+		done <- err
+	}()
+	stdout := os.Stdout
+	os.Stdout = pipe
+	failed, err := f()
+	os.Stdout = stdout
+	pipe.Close()
+	if copyErr := <-done; err == nil {
+		err = copyErr
+	}
+	return failed, err
+}
+
+// fingerprint hashes the names, sizes and modification times of the Go
+// files, go.mod and go.sum files beneath dir, leaving out the directories
+// the go command ignores as well, so that any edit, addition or removal
+// changes it
+func fingerprint(dir string) (uint64, error) {
+	h := fnv.New64a()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to check %s for changes: %s", dir, err)
+	}
+	return h.Sum64(), nil
+}
+
+// queryDaemon runs the subcommand, or prints the graph, on a daemon
+// listening at addr instead of scanning, passing on the flags of the query
+// given on the command line, and returns the exit status. The flags a query
+// cannot set have to be given when starting the daemon.
+func queryDaemon(addr, command string, args []string) int {
+	if command == "" {
+		command = "graph"
+	}
+	if command != "graph" && !replCommands[command] {
+		fatalf("%s does not work with -daemon", command)
+	}
+	if len(args) > 1 || (len(args) == 1 && args[0] == "-") {
+		usageError("-daemon takes at most one package name, the one the daemon scans")
+	}
+	allowed := stringSet(daemonFlags)
+	applied := stringSet(daemonQueryFlags)
+	ignored := stringSet(append(append([]string(nil), daemonOnlyFlags...), clientFlags...))
+	query, scan := url.Values{}, url.Values{}
+	flag.Visit(func(f *flag.Flag) {
+		switch {
+		case allowed[f.Name]:
+			query.Set(f.Name, f.Value.String())
+		case applied[f.Name] || f.Name == "cycles-json":
+			fatalf("-%s names a file or runs a command and can only be given when starting the daemon", f.Name)
+		case !ignored[f.Name]:
+			scan.Set(f.Name, f.Value.String())
+		}
+	})
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/"+command+"?"+query.Encode(), nil)
+	if err != nil {
+		fatalf("failed to query daemon: %s", err)
+	}
+	req.Header.Set(scanHeader, scan.Encode())
+	if len(args) == 1 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fatalf("failed to get cwd: %s", err)
+		}
+		req.Header.Set(packageHeader, args[0])
+		req.Header.Set(dirHeader, canonicalDir(cwd))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fatalf("failed to query daemon: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fatalf("daemon: %s", strings.TrimSpace(string(body)))
+	}
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		fatalf("failed to read daemon response: %s", err)
+	}
+	status, err := strconv.Atoi(resp.Header.Get(exitStatusHeader))
+	if err != nil {
+		fatalf("daemon sent no exit status")
+	}
+	return status
+}
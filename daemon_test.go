@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDaemonRefusesOtherScanSettings(t *testing.T) {
+	saved := *ignoreStdlib
+	t.Cleanup(func() { *ignoreStdlib = saved })
+	*ignoreStdlib = false
+	d := &daemon{pkg: "./cmd/app", root: "example.com/app/cmd/app"}
+	tests := []struct {
+		scan url.Values
+		want string
+	}{
+		{url.Values{}, ""},
+		{url.Values{"s": {"false"}}, ""},
+		{url.Values{"s": {"true"}}, `-s is "false" there, not "true"`},
+		{url.Values{"no-such-flag": {"1"}}, "unknown flag -no-such-flag"},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(scanHeader, tt.scan.Encode())
+		err := d.sameScan(h)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%v: %s", tt.scan, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%v: got %v, want %s", tt.scan, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// printDepguard prints a golangci-lint depguard configuration with one
+// strict rule per package below the base path, allowing exactly the imports
+// observed today. Standard library imports are allowed wholesale. The rules
+// are named by import path, and each covers the files of its package's
+// directory and none beneath it.
+func printDepguard(w io.Writer) error {
+	var names []string
+	for p, pkg := range pkgs {
+		if pkg.Goroot || isIgnored(pkg) || !inTree(p, basePath) {
+			continue
+		}
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# generated by godepgraph from the observed dependencies")
+	fmt.Fprintln(w, "linters-settings:")
+	fmt.Fprintln(w, "  depguard:")
+	fmt.Fprintln(w, "    rules:")
+	for _, p := range names {
+		dir := depguardDir(p)
+		fmt.Fprintf(w, "      %q:\n", p)
+		fmt.Fprintln(w, "        list-mode: strict")
+		fmt.Fprintln(w, "        files:")
+		fmt.Fprintf(w, "          - \"**/%s/*.go\"\n", dir)
+		fmt.Fprintf(w, "          - \"!**/%s/*/**\"\n", dir)
+		fmt.Fprintln(w, "          - \"!$test\"")
+		fmt.Fprintln(w, "        allow:")
+		fmt.Fprintln(w, "          - $gostd")
+		for _, imp := range pkgs[p].Imports {
+			if isStdlib(imp) || imp == "C" {
+				continue
+			}
+			fmt.Fprintf(w, "          - %s\n", imp)
+		}
+	}
+	return nil
+}
+
+// depguardDir returns the directory of a package below the base path as a
+// file glob matches it, from the directory of the base path on
+func depguardDir(importPath string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, basePath), "/")
+	if rel == "" {
+		return path.Base(basePath)
+	}
+	return path.Base(basePath) + "/" + rel
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"strings"
+	"testing"
+)
+
+func TestDepguardRules(t *testing.T) {
+	defer func(p map[string]*build.Package, b string) { pkgs, basePath = p, b }(pkgs, basePath)
+	resetFilters(t)
+	basePath = "example.com/app"
+	pkgs = map[string]*build.Package{
+		"example.com/app":       {ImportPath: "example.com/app", Imports: []string{"example.com/app/store", "fmt"}},
+		"example.com/app/root":  {ImportPath: "example.com/app/root"},
+		"example.com/app/store": {ImportPath: "example.com/app/store", Imports: []string{"github.com/lib/pq"}},
+		"example.com/appx":      {ImportPath: "example.com/appx"},
+	}
+	var buf bytes.Buffer
+	if err := printDepguard(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"      \"example.com/app\":\n        list-mode: strict\n        files:\n          - \"**/app/*.go\"\n          - \"!**/app/*/**\"\n",
+		"      \"example.com/app/root\":\n        list-mode: strict\n        files:\n          - \"**/app/root/*.go\"\n          - \"!**/app/root/*/**\"\n",
+		"      \"example.com/app/store\":\n        list-mode: strict\n        files:\n          - \"**/app/store/*.go\"\n          - \"!**/app/store/*/**\"\n",
+		"          - example.com/app/store\n",
+		"          - github.com/lib/pq\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	for _, bad := range []string{"appx", "\"*.go\"", "**//"} {
+		if strings.Contains(out, bad) {
+			t.Errorf("unexpected %q in\n%s", bad, out)
+		}
+	}
+}
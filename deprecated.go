@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// deprecation returns the deprecation message of a go.mod, "" if the module
+// is not deprecated. The message is a paragraph starting with "Deprecated:"
+// in the comment above or on the module directive.
+func deprecation(gomod []byte) string {
+	var comment []string
+	s := bufio.NewScanner(bytes.NewReader(gomod))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(line, "//"):
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+		case strings.HasPrefix(line, "module"):
+			if i := strings.Index(line, "//"); i >= 0 {
+				comment = append(comment, strings.TrimSpace(line[i+2:]))
+			}
+			for i, c := range comment {
+				if strings.HasPrefix(c, "Deprecated:") {
+					var msg []string
+					for _, m := range comment[i:] {
+						if m == "" {
+							break
+						}
+						msg = append(msg, m)
+					}
+					return strings.TrimSpace(strings.TrimPrefix(strings.Join(msg, " "), "Deprecated:"))
+				}
+			}
+			return ""
+		default:
+			comment = nil
+		}
+	}
+	return ""
+}
+
+// loadDeprecations looks up the go.mod of the latest version of the modules
+// on the module proxy, and returns the deprecation messages of those that
+// are deprecated
+func loadDeprecations(mods []string) map[string]string {
+	deprecated := make(map[string]string)
+	for _, mod := range mods {
+		latest, err := latestVersion(mod)
+		if err == nil && latest != "" {
+			var gomod []byte
+			if gomod, err = proxyGet(mod, "@v/"+latest+".mod"); err == nil {
+				if msg := deprecation(gomod); msg != "" {
+					deprecated[mod] = msg
+				}
+			}
+		}
+		if err != nil {
+			logger.Warn("failed to query module proxy", "module", mod, "err", err)
+		}
+	}
+	return deprecated
+}
+
+// markDeprecated looks up the go.mod of the latest version of every
+// external module on the module proxy, and outlines the packages of
+// deprecated modules in crimson, along with the edges to them and the
+// packages of the root's module importing them.
+func (g *graph) markDeprecated() {
+	deprecated := loadDeprecations(g.externalModules())
+	if len(deprecated) == 0 {
+		return
+	}
+
+	own := g.ownModule()
+	byID := make(map[string]*node)
+	for _, n := range g.Nodes {
+		byID[n.ID] = n
+		if msg, ok := deprecated[n.Module]; ok {
+			if n.Attrs == nil {
+				n.Attrs = attrs{}
+			}
+			n.Attrs.add(attrs{"color": "crimson", "penwidth": "3", "tooltip": "deprecated: " + msg})
+		}
+	}
+	importers := make(map[string][]string)
+	for _, e := range g.Edges {
+		from, to := byID[e.From], byID[e.To]
+		if from == nil || to == nil {
+			continue
+		}
+		if _, ok := deprecated[to.Module]; !ok || from.Module == to.Module {
+			continue
+		}
+		if e.Attrs == nil {
+			e.Attrs = attrs{}
+		}
+		e.Attrs["color"] = "crimson"
+		if from.Module == own {
+			if from.Attrs == nil {
+				from.Attrs = attrs{}
+			}
+			from.Attrs.add(attrs{"color": "crimson", "tooltip": "imports deprecated " + to.ID})
+			importers[to.Module] = append(importers[to.Module], from.ID)
+		}
+	}
+	for mod, msg := range deprecated {
+		logger.Warn("depending on deprecated module", "module", mod, "deprecation", msg, "importers", sortedSet(importers[mod]))
+	}
+}
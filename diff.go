@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// graphDiff lists what changed between two graphs. Edges are written as
+// "from -> to".
+type graphDiff struct {
+	AddedNodes     []string `json:"addedNodes,omitempty"`
+	RemovedNodes   []string `json:"removedNodes,omitempty"`
+	AddedEdges     []string `json:"addedEdges,omitempty"`
+	RemovedEdges   []string `json:"removedEdges,omitempty"`
+	AddedModules   []string `json:"addedModules,omitempty"`
+	RemovedModules []string `json:"removedModules,omitempty"`
+}
+
+func (d *graphDiff) empty() bool {
+	return len(d.AddedNodes)+len(d.RemovedNodes)+len(d.AddedEdges)+
+		len(d.RemovedEdges)+len(d.AddedModules)+len(d.RemovedModules) == 0
+}
+
+// diffGraphs compares two graphs by package import path, ignoring namespaces
+func diffGraphs(before, after *graph) *graphDiff {
+	d := &graphDiff{}
+	d.AddedNodes, d.RemovedNodes = diffSets(nodeSet(before), nodeSet(after))
+	d.AddedEdges, d.RemovedEdges = diffSets(edgeSet(before), edgeSet(after))
+	d.AddedModules, d.RemovedModules = diffSets(stringSet(before.externalModules()), stringSet(after.externalModules()))
+	return d
+}
+
+func nodeSet(g *graph) map[string]bool {
+	set := make(map[string]bool)
+	for _, n := range g.Nodes {
+		set[n.ID] = true
+	}
+	return set
+}
+
+func edgeSet(g *graph) map[string]bool {
+	set := make(map[string]bool)
+	for _, e := range g.Edges {
+		set[e.From+" -> "+e.To] = true
+	}
+	return set
+}
+
+// diffSets returns the sorted elements only in after and only in before
+func diffSets(before, after map[string]bool) (added, removed []string) {
+	for s := range after {
+		if !before[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range before {
+		if !after[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// writeDiffText prints a human-readable summary of the changes
+func writeDiffText(w io.Writer, d *graphDiff) {
+	if d.empty() {
+		fmt.Fprintln(w, "No dependency changes.")
+		return
+	}
+	section := func(title string, list []string) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s (%d):\n", title, len(list))
+		for _, s := range list {
+			fmt.Fprintf(w, "  %s\n", s)
+		}
+	}
+	section("New external modules", d.AddedModules)
+	section("Dropped external modules", d.RemovedModules)
+	section("New packages", d.AddedNodes)
+	section("Removed packages", d.RemovedNodes)
+	section("New imports", d.AddedEdges)
+	section("Removed imports", d.RemovedEdges)
+}
+
+// runDiff compares two JSON graphs, for example of a base branch and a pull
+// request, and prints the changes
+func runDiff(files []string) error {
+	if len(files) != 2 {
+		return fmt.Errorf("need an old and a new graph to diff")
+	}
+	before, err := readGraph(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := readGraph(files[1])
+	if err != nil {
+		return err
+	}
+
+	return printDiff(diffGraphs(before, after), after.ownModule())
+}
+
+// printDiff prints the changes in the style selected by -summary
+func printDiff(d *graphDiff, own string) error {
+	switch *diffSummary {
+	case "":
+		writeDiffText(os.Stdout, d)
+	case "text", "markdown":
+		writeDiffSummary(os.Stdout, d, *diffSummary == "markdown", own)
+	default:
+		return fmt.Errorf("unknown summary format %q", *diffSummary)
+	}
+	return nil
+}
+
+// writeDiffSummary prints one line per kind of change, suitable for posting
+// as a review comment. Packages of the own module are shortened by trim.
+func writeDiffSummary(w io.Writer, d *graphDiff, markdown bool, trim string) {
+	if d.empty() {
+		fmt.Fprintln(w, "No dependency changes.")
+		return
+	}
+
+	short := func(path string) string {
+		if trim != "" && strings.HasPrefix(path, trim+"/") {
+			return path[len(trim)+1:]
+		}
+		return path
+	}
+	code := func(s string) string {
+		if markdown {
+			return "`" + s + "`"
+		}
+		return s
+	}
+	line := func(sign, singular, plural string, list []string, edges bool) {
+		if len(list) == 0 {
+			return
+		}
+		noun := plural
+		if len(list) == 1 {
+			noun = singular
+		}
+		items := make([]string, len(list))
+		for i, s := range list {
+			if edges {
+				ends := strings.SplitN(s, " -> ", 2)
+				items[i] = code(short(ends[0])) + " → " + code(short(ends[1]))
+			} else {
+				items[i] = code(short(s))
+			}
+		}
+		if markdown {
+			fmt.Fprintf(w, "- **%s%d %s:** %s\n", sign, len(list), noun, strings.Join(items, ", "))
+		} else {
+			fmt.Fprintf(w, "%s%d %s: %s\n", sign, len(list), noun, strings.Join(items, ", "))
+		}
+	}
+
+	if markdown {
+		fmt.Fprintf(w, "#### Dependency changes\n\n")
+	}
+	line("+", "new external module", "new external modules", d.AddedModules, false)
+	line("-", "dropped external module", "dropped external modules", d.RemovedModules, false)
+	line("+", "new package", "new packages", d.AddedNodes, false)
+	line("-", "removed package", "removed packages", d.RemovedNodes, false)
+	line("+", "new import", "new imports", d.AddedEdges, true)
+	line("-", "removed import", "removed imports", d.RemovedEdges, true)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// directiveGroups holds the groups set by //godepgraph:group directives of
+// the scanned packages, which take precedence over -groups
+var directiveGroups = make(map[string]string)
+
+// directives returns the //godepgraph: directives in the package comments of
+// pkg, by name, like "group" for //godepgraph:group Payments. The first file
+// setting a directive wins.
+func directives(pkg *build.Package) map[string]string {
+	found := make(map[string]string)
+	if pkg.Goroot {
+		return found
+	}
+	fset := token.NewFileSet()
+	for _, name := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			logger.Warn("failed to parse package comment", "pkg", pkg.ImportPath, "err", err)
+			continue
+		}
+		if f.Doc == nil {
+			continue
+		}
+		for _, c := range f.Doc.List {
+			if !strings.HasPrefix(c.Text, "//godepgraph:") {
+				continue
+			}
+			fields := strings.SplitN(strings.TrimPrefix(c.Text, "//godepgraph:"), " ", 2)
+			if len(fields) != 2 || strings.TrimSpace(fields[1]) == "" {
+				logger.Warn("ignored directive without value", "pkg", pkg.ImportPath, "directive", c.Text)
+				continue
+			}
+			if _, ok := found[fields[0]]; !ok {
+				found[fields[0]] = strings.TrimSpace(fields[1])
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// docsPage is what a package page of the documentation site shows
+type docsPage struct {
+	Node      *node
+	Title     string
+	Imports   []string
+	Importers []string
+	Metrics   [][2]string
+	Graph     template.HTML
+}
+
+var docsFuncs = template.FuncMap{"page": docsPageName}
+
+var docsIndexTemplate = template.Must(template.New("index").Funcs(docsFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title><link rel="stylesheet" href="style.css"></head>
+<body><h1>{{.Title}}</h1>
+<ul>{{range .Nodes}}<li><a href="{{page .ID}}">{{.ID}}</a>{{if .Module}} <span class="module">{{.Module}}</span>{{end}}{{if .Description}} {{.Description}}{{end}}</li>
+{{end}}</ul>
+</body></html>
+`))
+
+var docsPageTemplate = template.Must(template.New("page").Funcs(docsFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Node.ID}}</title><link rel="stylesheet" href="style.css"></head>
+<body><p><a href="index.html">{{.Title}}</a></p>
+<h1>{{.Node.ID}}</h1>
+{{if .Node.Error}}<p class="error">{{.Node.Error}}</p>{{end}}
+{{if .Node.Description}}<p>{{.Node.Description}}</p>{{end}}
+{{if .Node.URL}}<p><a href="{{.Node.URL}}">Documentation</a></p>{{end}}
+<table>{{range .Metrics}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+<div class="graph">{{.Graph}}</div>
+<h2>Imports</h2>
+<ul>{{range .Imports}}<li><a href="{{page .}}">{{.}}</a></li>
+{{else}}<li>none</li>{{end}}</ul>
+<h2>Imported by</h2>
+<ul>{{range .Importers}}<li><a href="{{page .}}">{{.}}</a></li>
+{{else}}<li>none</li>{{end}}</ul>
+</body></html>
+`))
+
+const docsStyle = `body { font-family: sans-serif; margin: 2em; }
+th { text-align: left; padding-right: 1em; }
+.module { color: grey; }
+.error { color: red; }
+.graph { margin: 1em 0; overflow: auto; }
+`
+
+// docsPageEscaper turns node IDs into file names injectively: / becomes _,
+// and ! starts the escapes of the characters that would clash with it
+var docsPageEscaper = strings.NewReplacer("!", "!!", "_", "!_", "/", "_", ":", "!c", "\\", "!b")
+
+// docsPageName returns the file name of the page of a package
+func docsPageName(id string) string {
+	return docsPageEscaper.Replace(id) + ".html"
+}
+
+// writeDocs writes a static site to dir with an index of all packages and a
+// page per package, with its imports, importers, metrics and the graph of its
+// direct neighbours
+func writeDocs(dir string, g *graph) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create docs directory: %s", err)
+	}
+	title := "Packages of " + g.Namespace
+
+	imports := make(map[string][]string)
+	importers := make(map[string][]string)
+	for _, e := range g.Edges {
+		imports[e.From] = append(imports[e.From], e.To)
+		importers[e.To] = append(importers[e.To], e.From)
+	}
+
+	nodes := append([]*node(nil), g.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	write := func(name string, t *template.Template, data interface{}) error {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write docs: %s", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte(docsStyle), 0644); err != nil {
+		return fmt.Errorf("failed to write docs: %s", err)
+	}
+	if err := write("index.html", docsIndexTemplate, struct {
+		Title string
+		Nodes []*node
+	}{title, nodes}); err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		page := &docsPage{
+			Node:      n,
+			Title:     title,
+			Imports:   sortedSet(imports[n.ID]),
+			Importers: sortedSet(importers[n.ID]),
+		}
+		page.Metrics = docsMetrics(g, n, len(page.Imports), len(page.Importers))
+		var svg bytes.Buffer
+		if err := writeSVG(&svg, g.neighbourhood(n.ID)); err != nil {
+			return err
+		}
+		page.Graph = template.HTML(svg.String())
+		if err := write(docsPageName(n.ID), docsPageTemplate, page); err != nil {
+			return err
+		}
+	}
+	logger.Info("wrote docs", "dir", dir, "pages", len(nodes))
+	return nil
+}
+
+// docsMetrics lists what is known about a package
+func docsMetrics(g *graph, n *node, imports, importers int) [][2]string {
+	metrics := [][2]string{
+		{"module", n.Module},
+		{"dependency", n.Dependency},
+		{"imports", fmt.Sprint(imports)},
+		{"imported by", fmt.Sprint(importers)},
+	}
+	if n.Files > 0 {
+		metrics = append(metrics, [2]string{"files", fmt.Sprint(n.Files)}, [2]string{"lines", fmt.Sprint(n.Lines)})
+	}
+	if !n.Ghost && n.Error == "" {
+		metrics = append(metrics, [2]string{"depth", fmt.Sprint(g.depth(n.ID))})
+	}
+	if n.Stdlib {
+		metrics = append(metrics, [2]string{"standard library", "yes"})
+	}
+	if n.Cgo {
+		metrics = append(metrics, [2]string{"cgo", "yes"})
+	}
+	// whatever the overlays annotated the node with, the description has a
+	// paragraph of its own
+	var notes []string
+	for _, note := range strings.Split(n.Attrs["tooltip"], "\n") {
+		if note != "" && note != n.Description {
+			notes = append(notes, note)
+		}
+	}
+	metrics = append(metrics, [2]string{"notes", strings.Join(notes, "; ")})
+	var kept [][2]string
+	for _, m := range metrics {
+		if m[1] != "" {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// neighbourhood returns the graph of a node, its imports and its importers
+func (g *graph) neighbourhood(id string) *graph {
+	near := map[string]bool{id: true}
+	sub := &graph{Namespace: g.Namespace}
+	for _, e := range g.Edges {
+		if e.From == id || e.To == id {
+			near[e.From], near[e.To] = true, true
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	for _, n := range g.Nodes {
+		if near[n.ID] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	return sub
+}
+
+// sortedSet returns the distinct entries of list in order
+func sortedSet(list []string) []string {
+	seen := make(map[string]bool)
+	var set []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			set = append(set, s)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
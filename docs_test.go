@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestDocsPageNamesDoNotCollide(t *testing.T) {
+	ids := []string{"a/b_c", "a_b/c", "a/b/c", "a_b_c", "a!_b", "a!/b", "ns:a/b", "ns/a/b", `a\b`}
+	pages := make(map[string]string)
+	for _, id := range ids {
+		page := docsPageName(id)
+		if other, ok := pages[page]; ok {
+			t.Errorf("%s and %s both get page %s", other, id, page)
+		}
+		pages[page] = id
+	}
+	if got := docsPageName("example.com/app/store"); got != "example.com_app_store.html" {
+		t.Errorf("page of example.com/app/store is %s", got)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// out receives the DOT output
+var out io.Writer = os.Stdout
+
+func writeDot(g *graph) error {
+	fmt.Fprintln(out, "digraph godep {")
+	if *concentrate {
+		fmt.Fprintln(out, "concentrate=true;")
+	}
+	if *showSimplified && len(g.Simplifications) > 0 {
+		// left-justified lines below the drawing
+		label := `simplified:\l`
+		for _, line := range g.Simplifications {
+			label += dotEscaper.Replace(line) + `\l`
+		}
+		fmt.Fprintf(out, "label=\"%s\";\nlabelloc=\"b\";\nlabeljust=\"l\";\n", label)
+	}
+
+	// merged graphs get one box per namespace, single graphs on request
+	clusters := g.namespaces()
+	boxed := len(clusters) > 1 || (*subgraph && g.Namespace != "")
+
+	edges := make(map[string][]*edge)
+	seen := make(map[string]bool)
+	for _, e := range g.Edges {
+		key := g.namespaceOf(e.Namespace) + "\x00" + e.From
+		// with -concentrate, the first of several edges between the same
+		// nodes stands for all of them
+		if *concentrate {
+			if seen[key+"\x00"+e.To] {
+				continue
+			}
+			seen[key+"\x00"+e.To] = true
+		}
+		edges[key] = append(edges[key], e)
+	}
+
+	grouped := false
+	for _, n := range g.Nodes {
+		grouped = grouped || n.Group != ""
+	}
+	if grouped {
+		// edges between groups connect their boxes
+		fmt.Fprintln(out, "compound=true;")
+	}
+
+	networkPackages := make(map[string]*node)
+	for _, namespace := range clusters {
+		if boxed {
+			printSubgraphHead(namespace)
+		}
+
+		groupOf := make(map[string]string)
+		var groupNames []string
+		members := make(map[string][]*node)
+		for _, n := range g.Nodes {
+			if g.namespaceOf(n.Namespace) != namespace || n.Group == "" {
+				continue
+			}
+			groupOf[n.ID] = n.Group
+			if members[n.Group] == nil {
+				groupNames = append(groupNames, n.Group)
+			}
+			members[n.Group] = append(members[n.Group], n)
+		}
+
+		// edges between two groups are counted, not drawn one by one
+		var crossing [][2]string
+		crossCount := make(map[[2]string]int)
+		draw := func(n *node) {
+			printNode(namespace, n.ID, n.Color, n.Attrs)
+			for _, e := range edges[namespace+"\x00"+n.ID] {
+				from, to := groupOf[e.From], groupOf[e.To]
+				if from == "" || to == "" || from == to {
+					printEdge(namespace, e.From, e.To, e.Attrs)
+					continue
+				}
+				pair := [2]string{from, to}
+				if crossCount[pair] == 0 {
+					crossing = append(crossing, pair)
+				}
+				crossCount[pair]++
+			}
+
+			// check if we need to build a network subgraph for this node later
+			if *networkSubgraphs &&
+				hasPrefixes(n.ID, includedPackages) &&
+				!strings.HasPrefix(n.ID, namespace) {
+				networkPackages[namespace+"\x00"+n.ID] = n
+			}
+		}
+
+		for _, n := range g.Nodes {
+			if g.namespaceOf(n.Namespace) == namespace && n.Group == "" {
+				draw(n)
+			}
+		}
+		for _, group := range groupNames {
+			printClusterHead(ns(namespace, group), group)
+			for _, n := range members[group] {
+				draw(n)
+			}
+			fmt.Fprintln(out, "}")
+		}
+		for _, pair := range crossing {
+			from, to := members[pair[0]][0], members[pair[1]][0]
+			printEdge(namespace, from.ID, to.ID, attrs{
+				"label": fmt.Sprintf("%d", crossCount[pair]),
+				"ltail": "cluster" + ns(namespace, pair[0]),
+				"lhead": "cluster" + ns(namespace, pair[1]),
+			})
+		}
+
+		if boxed {
+			fmt.Fprintln(out, "}")
+		}
+	}
+
+	for key, n := range networkPackages {
+		nodeNS := strings.SplitN(key, "\x00", 2)[0]
+		// make subgraph
+		nameSplit := strings.Split(n.ID, "/")
+		name := nameSplit[len(nameSplit)-1]
+		printSubgraphHead(name)
+		printNode(nodeNS, name, "paleturquoise", nil)
+		fmt.Fprintln(out, "}")
+
+		// make edge
+		printEdge(nodeNS, n.ID, name, nil)
+	}
+
+	fmt.Fprintln(out, "}")
+	return nil
+}
+
+func printSubgraphHead(name string) {
+	fmt.Fprintf(out, "subgraph \"cluster%s\" {\n", name)
+	fmt.Fprintln(out, "style=filled;")
+	fmt.Fprintln(out, "color=lightgrey;")
+	fmt.Fprintf(out, "label=\"%s\"\n", dotEscaper.Replace(name))
+}
+
+// printClusterHead opens the box of a group, which stands out from the
+// lightgrey box of its namespace
+func printClusterHead(id, label string) {
+	fmt.Fprintf(out, "subgraph \"cluster%s\" {\n", id)
+	fmt.Fprintln(out, "style=\"filled,rounded\";")
+	fmt.Fprintln(out, "color=white;")
+	fmt.Fprintf(out, "label=\"%s\"\n", dotEscaper.Replace(label))
+}
+
+func printNode(namespace, name, color string, extra attrs) {
+	a := attrs{"label": name, "style": "filled", "color": color}
+	// overlays changing the outline color must not change the fill
+	if _, ok := extra["color"]; ok {
+		a["fillcolor"] = color
+	}
+	a.add(extra)
+	fmt.Fprintf(out, "\"%s\" [%s];\n", ns(namespace, name), a)
+}
+
+func printEdge(namespace, source, dest string, extra attrs) {
+	if len(extra) == 0 {
+		fmt.Fprintf(out, "\"%s\" -> \"%s\";\n", ns(namespace, source), ns(namespace, dest))
+		return
+	}
+	fmt.Fprintf(out, "\"%s\" -> \"%s\" [%s];\n", ns(namespace, source), ns(namespace, dest), extra)
+}
+
+// namespace all nodes to unique nodes when combining several graphs. Nodes
+// without a namespace keep their plain name.
+func ns(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return fmt.Sprintf("%s:%s", namespace, name)
+}
+
+// dotEscaper quotes attribute values. Backslashes are escaped too, so text
+// like a trailing backslash in a description cannot break the quoting, and
+// line breaks become the \n of dot.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", " ")
+
+// attrs holds DOT attributes of a node or edge
+type attrs map[string]string
+
+// add merges b into a. Labels and tooltips are appended line by line, so
+// several overlays can annotate the same node.
+func (a attrs) add(b attrs) {
+	for k, v := range b {
+		if old, ok := a[k]; ok && old != "" && (k == "label" || k == "tooltip") {
+			v = old + "\n" + v
+		}
+		a[k] = v
+	}
+}
+
+// String renders the attributes in a stable order, label first
+func (a attrs) String() string {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		if k != "label" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := a["label"]; ok {
+		keys = append([]string{"label"}, keys...)
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=\"%s\"", k, dotEscaper.Replace(a[k]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDotEscaping(t *testing.T) {
+	tests := []struct {
+		name, value, want string
+	}{
+		{"trailing backslash", `C:\build\`, `C:\\build\\`},
+		{"quote", `say "hi"`, `say \"hi\"`},
+		{"escaped quote", `\"`, `\\\"`},
+		{"line break", "a\nb", `a\nb`},
+		{"tab", "a\tb", "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dotEscaper.Replace(tt.value); got != tt.want {
+				t.Errorf("escaped %q to %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrailingBackslashIsValidDot(t *testing.T) {
+	g := &graph{Nodes: []*node{{
+		ID:          "example.com/app",
+		Color:       "paleturquoise",
+		Description: `builds in C:\work\`,
+		Attrs:       attrs{"tooltip": `builds in C:\work\`},
+	}}}
+	g.Nodes[0].Attrs.add(attrs{"tooltip": "owned by platform"})
+	var buf bytes.Buffer
+	if err := renderDot(&buf, g, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateDot(buf.String()); err != nil {
+		t.Fatalf("invalid DOT: %s\n%s", err, buf.String())
+	}
+	if want := `tooltip="builds in C:\\work\\\nowned by platform"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %s in\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"sort"
+	"strings"
+)
+
+// runDryRun resolves the root packages, reports conflicting settings and
+// describes what a real run would scan and print. It returns false if a
+// root cannot be resolved or the settings conflict.
+func runDryRun(cwd, command string, rootArgs []string) bool {
+	ok := true
+	problem := func(format string, args ...interface{}) {
+		fmt.Printf("problem: "+format+"\n", args...)
+		ok = false
+	}
+
+	fmt.Println("roots:")
+	for _, arg := range rootArgs {
+		// resolved like the scan does, with -constraints and -goroot
+		pkg, err := importPackage(arg, cwd)
+		if err != nil {
+			problem("cannot resolve %s: %s", arg, err)
+			continue
+		}
+		if build.IsLocalImport(pkg.ImportPath) {
+			pkg.ImportPath = moduleImportPath(pkg.Dir, pkg.ImportPath)
+		}
+		fmt.Printf("  %s (%s)\n", pkg.ImportPath, pkg.Dir)
+		if reason := ignoreReason(pkg); reason != "" {
+			problem("root %s is itself filtered out: %s", pkg.ImportPath, reason)
+		}
+	}
+
+	if basePath != "" {
+		fmt.Printf("base path: %s\n", basePath)
+	} else {
+		fmt.Println("base path: derived from the first root")
+	}
+
+	fmt.Println("scan:")
+	fmt.Printf("  standard library: %s\n", choose(*ignoreStdlib, "ignored", choose(*stdlibEdges, "followed", "shown, not followed")))
+	if len(ignoredPrefixes) > 0 {
+		fmt.Printf("  ignored prefixes: %s\n", strings.Join(ignoredPrefixes, ", "))
+	}
+	var ignoredNames []string
+	for p := range ignored {
+		if p != "C" {
+			ignoredNames = append(ignoredNames, p)
+		}
+	}
+	sort.Strings(ignoredNames)
+	for _, p := range ignoredNames {
+		fmt.Printf("  ignored package: %s\n", p)
+	}
+	if len(includedPackages) > 0 {
+		fmt.Printf("  always included: %s\n", strings.Join(includedPackages, ", "))
+	}
+	if *filterByBasePath {
+		fmt.Println("  only packages in the base path")
+	}
+	for _, r := range scopedRules {
+		var actions []string
+		if r.noTests {
+			actions = append(actions, "no test imports")
+		}
+		if r.noImports {
+			actions = append(actions, "no imports")
+		}
+		for _, p := range r.ignore {
+			actions = append(actions, "ignoring "+p)
+		}
+		fmt.Printf("  beneath %s: %s\n", r.scope, strings.Join(actions, ", "))
+	}
+	fmt.Printf("  unresolvable packages: %s\n", choose(*strict, "fail the run", "drawn as error nodes"))
+
+	// settings cancelling each other out
+	for _, p := range ignoredNames {
+		if hasPrefixes(p, includedPackages) {
+			problem("%s is both ignored (-i) and included (-n)", p)
+		}
+	}
+	for _, prefix := range ignoredPrefixes {
+		for _, inc := range includedPackages {
+			if strings.HasPrefix(inc, prefix) || strings.HasPrefix(prefix, inc) {
+				problem("prefix %s is both ignored (-p) and included (-n) as %s, included packages win", prefix, inc)
+			}
+		}
+	}
+	if *stdlibEdges && *ignoreStdlib {
+		problem("-stdlib-edges has no effect with -s")
+	}
+	if *networkSubgraphs && !*subgraph {
+		problem("-network-subgraphs requires -subgraph")
+	}
+	if *showComplexity && *gocycloFile != "" {
+		fmt.Println("note: -gocyclo replaces the computed complexity of -complexity")
+	}
+	if *renderFormat != "" && *outputFormat != "dot" {
+		problem("-render only applies to the dot format, not %s", *outputFormat)
+	}
+
+	fmt.Println("output:")
+	switch {
+	case command != "":
+		fmt.Printf("  %s subcommand\n", command)
+	case *renderFormat != "":
+		fmt.Printf("  dot rendered to %s\n", *renderFormat)
+	default:
+		fmt.Printf("  %s\n", *outputFormat)
+	}
+	if names := enabledOverlays(); len(names) > 0 {
+		fmt.Printf("  overlays: %s\n", strings.Join(names, ", "))
+	}
+	return ok
+}
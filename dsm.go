@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// partition orders the nodes of the graph for a dependency structure matrix:
+// every package comes after the packages it imports, and the members of an
+// import cycle are kept together as one block. It returns the blocks in
+// order, each sorted by name.
+func (g *graph) partition() [][]string {
+	var ids []string
+	for _, n := range g.Nodes {
+		if !n.Ghost && n.Error == "" {
+			ids = append(ids, n.ID)
+		}
+	}
+	sort.Strings(ids)
+	next := g.imports()
+
+	block := make(map[string]int)
+	var blocks [][]string
+	for _, scc := range stronglyConnected(ids, next) {
+		sort.Strings(scc)
+		for _, id := range scc {
+			block[id] = len(blocks)
+		}
+		blocks = append(blocks, scc)
+	}
+	for _, id := range ids {
+		if _, ok := block[id]; !ok {
+			block[id] = len(blocks)
+			blocks = append(blocks, []string{id})
+		}
+	}
+
+	// depth-first over the blocks, emitting a block once all blocks it
+	// imports have been emitted
+	done := make(map[int]bool)
+	var order [][]string
+	var visit func(b int)
+	visit = func(b int) {
+		if done[b] {
+			return
+		}
+		done[b] = true
+		for _, id := range blocks[b] {
+			for _, imp := range next(id) {
+				if c, ok := block[imp]; ok {
+					visit(c)
+				}
+			}
+		}
+		order = append(order, blocks[b])
+	}
+	for _, id := range ids {
+		visit(block[id])
+	}
+	return order
+}
+
+// writeDSM prints the partitioned dependency structure matrix of the graph:
+// the suggested order of the packages, with import cycles marked as blocks,
+// and the matrix in that order. A cell of row i and column j holds the
+// weight of the import of package i on package j, the number of identifiers
+// used across it with -symbols and 1 otherwise. Imports on earlier packages
+// fall below the diagonal, only imports within a cycle above it.
+func writeDSM(w io.Writer, g *graph) error {
+	weights := make(map[[2]string]int)
+	for _, e := range g.Edges {
+		weight := e.Symbols
+		if weight == 0 {
+			weight = 1
+		}
+		weights[[2]string{e.From, e.To}] += weight
+	}
+
+	var order []string
+	fmt.Fprintln(w, "order:")
+	cycle := 0
+	for _, b := range g.partition() {
+		mark := ""
+		if len(b) > 1 {
+			cycle++
+			mark = fmt.Sprintf("  [cycle %d]", cycle)
+		}
+		for _, id := range b {
+			order = append(order, id)
+			fmt.Fprintf(w, "%4d  %s%s\n", len(order), id, mark)
+		}
+	}
+
+	width := len(fmt.Sprint(len(order)))
+	for _, v := range weights {
+		if l := len(fmt.Sprint(v)); l > width {
+			width = l
+		}
+	}
+	cell := func(s string) string {
+		return strings.Repeat(" ", width+1-len(s)) + s
+	}
+	fmt.Fprintln(w, "matrix:")
+	fmt.Fprint(w, "    ")
+	for j := range order {
+		fmt.Fprint(w, cell(fmt.Sprint(j+1)))
+	}
+	fmt.Fprintln(w)
+	for i, from := range order {
+		fmt.Fprintf(w, "%4d", i+1)
+		for j, to := range order {
+			switch v := weights[[2]string{from, to}]; {
+			case v > 0:
+				fmt.Fprint(w, cell(fmt.Sprint(v)))
+			case i == j:
+				fmt.Fprint(w, cell("\\"))
+			default:
+				fmt.Fprint(w, cell("."))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
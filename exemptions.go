@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exemption excuses a module or a package in an import cycle from the
+// budgets of the check subcommand until it expires
+type exemption struct {
+	subject string
+	owner   string
+	expires time.Time
+	used    bool
+}
+
+// loadExemptions reads a file of "subject = owner yyyy-mm-dd" lines, the
+// subject being an external module or a package in an import cycle, and
+// the date the last day the exemption holds
+func loadExemptions(name string) (map[string]*exemption, error) {
+	config, err := readConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	exemptions := make(map[string]*exemption)
+	for subject, value := range config {
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("exemption of %s: expected owner and expiry date", subject)
+		}
+		date, err := time.ParseInLocation("2006-01-02", fields[len(fields)-1], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("exemption of %s: invalid expiry date: %s", subject, err)
+		}
+		exemptions[subject] = &exemption{
+			subject: subject,
+			owner:   strings.Join(fields[:len(fields)-1], " "),
+			expires: date.AddDate(0, 0, 1),
+		}
+	}
+	return exemptions, nil
+}
+
+func (e *exemption) expired() bool {
+	return !time.Now().Before(e.expires)
+}
+
+func (e *exemption) String() string {
+	return fmt.Sprintf("%s, owner %s, until %s", e.subject, e.owner, e.expires.AddDate(0, 0, -1).Format("2006-01-02"))
+}
+
+// exempt looks up the active exemption of any of the subjects and marks it
+// used. Expired exemptions are marked used as well, but do not exempt.
+func exempt(exemptions map[string]*exemption, subjects ...string) *exemption {
+	for _, subject := range subjects {
+		if e, ok := exemptions[subject]; ok {
+			e.used = true
+			if !e.expired() {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// reportExemptions prints the exemptions that found use, and fails on the
+// expired ones that still would. It returns false if any did.
+func reportExemptions(exemptions map[string]*exemption) bool {
+	var subjects []string
+	for subject := range exemptions {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	ok := true
+	for _, subject := range subjects {
+		e := exemptions[subject]
+		switch {
+		case !e.used:
+			fmt.Printf("%-4s exemption no longer needed: %s\n", "ok", e)
+		case e.expired():
+			fmt.Printf("%-4s exemption expired: %s\n", "FAIL", e)
+			ok = false
+		default:
+			fmt.Printf("%-4s exemption active: %s\n", "ok", e)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"sort"
+)
+
+// omitted holds the packages the filters removed from the graph, and which
+// filter it was
+var omitted = make(map[string]string)
+
+// ignoreReason returns which filter removes pkg from the graph, or "" if
+// none does
+func ignoreReason(pkg *build.Package) string {
+	if hasPrefixes(pkg.ImportPath, includedPackages) {
+		return ""
+	}
+	if ignored[pkg.ImportPath] {
+		return "ignored by -i"
+	}
+	for _, p := range ignoredPatterns {
+		if matchGlob(p, pkg.ImportPath) {
+			return fmt.Sprintf("pattern %s ignored by -i", p)
+		}
+	}
+	switch {
+	case pkg.Goroot && *ignoreStdlib:
+		return "standard library, ignored by -s"
+	case isNotOfBasepath(pkg.ImportPath, basePath):
+		return fmt.Sprintf("outside the base path %s, ignored by -b", basePath)
+	}
+	for _, p := range ignoredPrefixes {
+		if matchesPrefix(pkg.ImportPath, p) {
+			return fmt.Sprintf("%s %s ignored by -p", choose(isGlob(p), "pattern", "prefix"), p)
+		}
+	}
+	return ""
+}
+
+// setupFilters turns the filter flags, however they were set, into the
+// lists ignoreReason and the scoped rules check
+func setupFilters() error {
+	if *ignorePrefixes != "" {
+		ignoredPrefixes = sanitizeCSV(*ignorePrefixes)
+	}
+	if *ignorePackages != "" {
+		for _, p := range sanitizeCSV(*ignorePackages) {
+			ignored[p] = true
+			if isGlob(p) {
+				ignoredPatterns = append(ignoredPatterns, p)
+			}
+		}
+	}
+	if *scopedFile != "" {
+		rules, err := loadScoped(*scopedFile)
+		if err != nil {
+			return err
+		}
+		scopedRules = rules
+	}
+	if *includePackages != "" {
+		includedPackages = sanitizeCSV(*includePackages)
+	}
+	return nil
+}
+
+// skip notes that a package was left out, and why
+func skip(pkgName, reason string) {
+	logger.Info("skipped package", "pkg", pkgName, "reason", reason)
+	if _, ok := omitted[pkgName]; !ok && pkgName != "C" {
+		omitted[pkgName] = reason
+	}
+}
+
+// printOmitted lists every package that was encountered but left out, with
+// the filter that removed it
+func printOmitted() {
+	var names []string
+	for name := range omitted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, omitted[name])
+	}
+}
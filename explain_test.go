@@ -0,0 +1,113 @@
+package main
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// filterFlags are the flags the filter tests set, restored after each test
+var filterFlags = []*string{ignorePackages, ignorePrefixes, includePackages, scopedFile}
+
+// resetFilters clears the filters, and restores the filter flags and lists
+// when the test ends
+func resetFilters(t *testing.T) {
+	saved := make([]string, len(filterFlags))
+	for i, f := range filterFlags {
+		saved[i] = *f
+		*f = ""
+	}
+	stdlib, base, bp := *ignoreStdlib, *filterByBasePath, basePath
+	clear := func() {
+		ignored = map[string]bool{"C": true}
+		ignoredPatterns, ignoredPrefixes, includedPackages, scopedRules = nil, nil, nil, nil
+	}
+	clear()
+	t.Cleanup(func() {
+		for i, f := range filterFlags {
+			*f = saved[i]
+		}
+		*ignoreStdlib, *filterByBasePath, basePath = stdlib, base, bp
+		clear()
+	})
+}
+
+func TestIgnorePrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		i, p, n string
+		s, b    bool
+		pkg     string
+		stdlib  bool
+		want    string
+	}{
+		{name: "nothing matches", pkg: "example.com/app/store"},
+		{name: "-i by name", i: "example.com/app/store", pkg: "example.com/app/store", want: "ignored by -i"},
+		{name: "-i by ** pattern", i: "**/internal/**", pkg: "example.com/app/internal/db", want: "pattern **/internal/** ignored by -i"},
+		{name: "-i ** matches no elements", i: "example.com/**/db", pkg: "example.com/db", want: "pattern example.com/**/db ignored by -i"},
+		{name: "-p by prefix", p: "github.com/", pkg: "github.com/lib/pq", want: "prefix github.com/ ignored by -p"},
+		{name: "-p by pattern", p: "github.com/*/mocks", pkg: "github.com/foo/mocks", want: "pattern github.com/*/mocks ignored by -p"},
+		{name: "-p pattern matches whole paths", p: "github.com/*/mocks", pkg: "github.com/foo/mocks/sub"},
+		{name: "-s", s: true, pkg: "fmt", stdlib: true, want: "standard library, ignored by -s"},
+		{name: "-b", b: true, pkg: "github.com/lib/pq", want: "outside the base path example.com/app, ignored by -b"},
+		{name: "-n beats -i", i: "example.com/app/store", n: "example.com/app/store", pkg: "example.com/app/store"},
+		{name: "-n pattern beats -i pattern", i: "**/internal/**", n: "example.com/**", pkg: "example.com/app/internal/db"},
+		{name: "-n beats -s", s: true, n: "fmt", pkg: "fmt", stdlib: true},
+		{name: "-n beats -b", b: true, n: "github.com/lib/**", pkg: "github.com/lib/pq"},
+		{name: "-n beats -p", p: "github.com/", n: "github.com/lib/pq", pkg: "github.com/lib/pq"},
+		{name: "-i before -s", i: "fmt", s: true, pkg: "fmt", stdlib: true, want: "ignored by -i"},
+		{name: "-s before -b", s: true, b: true, pkg: "fmt", stdlib: true, want: "standard library, ignored by -s"},
+		{name: "-b before -p", b: true, p: "github.com/", pkg: "github.com/lib/pq", want: "outside the base path example.com/app, ignored by -b"},
+		{name: "-i pattern before -p", i: "github.com/**", p: "github.com/", pkg: "github.com/lib/pq", want: "pattern github.com/** ignored by -i"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFilters(t)
+			*ignorePackages, *ignorePrefixes, *includePackages = tt.i, tt.p, tt.n
+			*ignoreStdlib, *filterByBasePath, basePath = tt.s, tt.b, "example.com/app"
+			if err := setupFilters(); err != nil {
+				t.Fatal(err)
+			}
+			got := ignoreReason(&build.Package{ImportPath: tt.pkg, Goroot: tt.stdlib})
+			if got != tt.want {
+				t.Errorf("ignoreReason(%s) = %q, want %q", tt.pkg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopedIgnorePrecedence(t *testing.T) {
+	resetFilters(t)
+	name := filepath.Join(t.TempDir(), "scoped.txt")
+	rules := "example.com/app/legacy = ignore github.com/old/**\nexample.com/app/** = ignore github.com/debug\n"
+	if err := os.WriteFile(name, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	*scopedFile = name
+	if err := setupFilters(); err != nil {
+		t.Fatal(err)
+	}
+	imps := []string{"github.com/old/log", "github.com/debug", "fmt"}
+	tests := []struct {
+		pkg  string
+		want []string
+	}{
+		{"example.com/app/legacy", []string{"fmt"}},
+		{"example.com/app/api", []string{"github.com/old/log", "fmt"}},
+		{"example.com/other", imps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pkg, func(t *testing.T) {
+			got := scopedImports(&build.Package{ImportPath: tt.pkg}, imps)
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("kept %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
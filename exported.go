@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+)
+
+// loadExported counts the exported identifiers of every visible package
+// outside the standard library: functions, types, variables, constants, and
+// the exported methods of exported types.
+func loadExported() (map[string]int, error) {
+	exported := make(map[string]int)
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot {
+			continue
+		}
+		fset := token.NewFileSet()
+		count := 0
+		for _, name := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+			f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %s", name, err)
+			}
+			count += exportedDecls(f)
+		}
+		exported[pkgName] = count
+	}
+	return exported, nil
+}
+
+func exportedDecls(f *ast.File) int {
+	count := 0
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			if decl.Recv == nil || ast.IsExported(receiverType(decl.Recv)) {
+				count++
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						count++
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							count++
+						}
+					}
+				}
+			}
+		}
+	}
+	return count
+}
+
+// receiverType returns the name of the type of a method receiver
+func receiverType(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func exportedAttrs(pkgName string, exported map[string]int) attrs {
+	n, ok := exported[pkgName]
+	if !ok {
+		return nil
+	}
+	return attrs{"label": fmt.Sprintf("(%d exported)", n)}
+}
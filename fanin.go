@@ -0,0 +1,36 @@
+package main
+
+import "fmt"
+
+// fanInBorders draws the border of every node as thick as the share of the
+// packages importing it, relative to the most imported one. The outline
+// turns black unless an overlay already colors it, the fill stays.
+func (g *graph) fanInBorders() {
+	importers := make(map[string]map[string]bool)
+	for _, e := range g.Edges {
+		if importers[e.To] == nil {
+			importers[e.To] = make(map[string]bool)
+		}
+		importers[e.To][e.From] = true
+	}
+	max := 1
+	for _, from := range importers {
+		if len(from) > max {
+			max = len(from)
+		}
+	}
+	for _, n := range g.Nodes {
+		count := len(importers[n.ID])
+		if count == 0 || n.Ghost {
+			continue
+		}
+		if n.Attrs == nil {
+			n.Attrs = attrs{}
+		}
+		if _, ok := n.Attrs["color"]; !ok {
+			n.Attrs["color"] = "black"
+		}
+		n.Attrs["penwidth"] = fmt.Sprintf("%.1f", 1+5*float64(count)/float64(max))
+		n.Attrs.add(attrs{"tooltip": fmt.Sprintf("imported by %d %s", count, choose(count == 1, "package", "packages"))})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// splitFiles turns the packages in the base path into boxes of their Go
+// files, with the edges going out from the files importing a package
+// instead of from the package. The package nodes stay, for the edges from
+// other files to point at. The files only some -constraints or -platforms
+// sets build are drawn too, marked with those sets, and keep the marks of
+// the conditional imports they make.
+func (g *graph) splitFiles() error {
+	split := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if pkg, ok := pkgs[n.ID]; ok && !pkg.Goroot && strings.HasPrefix(n.ID, basePath) {
+			split[n.ID] = true
+		}
+	}
+
+	outgoing := make(map[[2]string]*edge)
+	var edges []*edge
+	for _, e := range g.Edges {
+		if split[e.From] && !e.Test {
+			outgoing[[2]string{e.From, e.To}] = e
+			continue
+		}
+		edges = append(edges, e)
+	}
+
+	var files []*node
+	for _, n := range g.Nodes {
+		if !split[n.ID] {
+			continue
+		}
+		pkg := pkgs[n.ID]
+		names, gated := buildFiles(pkg)
+		fset := token.NewFileSet()
+		for _, name := range names {
+			f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ImportsOnly)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %s", name, err)
+			}
+			id := n.ID + "/" + name
+			file := &node{
+				ID:          id,
+				Module:      n.Module,
+				Group:       n.ID,
+				Color:       n.Color,
+				Constraints: gated[name],
+				Attrs:       attrs{"shape": "note"},
+			}
+			if file.Constraints != nil {
+				file.Attrs.add(constraintNodeAttrs(file.Constraints))
+			}
+			files = append(files, file)
+			for _, spec := range f.Imports {
+				imp, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					continue
+				}
+				if e, ok := outgoing[[2]string{n.ID, imp}]; ok {
+					edges = append(edges, &edge{Namespace: e.Namespace, From: id, To: imp, Blank: e.Blank, Constraints: e.Constraints, Attrs: e.Attrs})
+				}
+			}
+		}
+	}
+	g.Nodes = append(g.Nodes, files...)
+	g.Edges = edges
+	return nil
+}
+
+// buildFiles returns the Go files of pkg that the default build context or
+// any of the constraint sets build, and for the files that only some of them
+// build, the names of those
+func buildFiles(pkg *build.Package) ([]string, map[string][]string) {
+	names := append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...)
+	if len(constraintSets) == 0 {
+		return names, nil
+	}
+	sets := make(map[string][]string)
+	for _, name := range names {
+		sets[name] = []string{defaultSetName()}
+	}
+	for _, name := range pkg.IgnoredGoFiles {
+		if !strings.HasSuffix(name, "_test.go") {
+			names = append(names, name)
+		}
+	}
+	for _, set := range constraintSets {
+		if set.name == defaultSetName() {
+			continue
+		}
+		for _, name := range names {
+			if ok, err := set.ctx.MatchFile(pkg.Dir, name); err == nil && ok {
+				sets[name] = append(sets[name], set.name)
+			}
+		}
+	}
+	all := len(constraintSetNames())
+	built := names[:0]
+	gated := make(map[string][]string)
+	for _, name := range names {
+		switch len(sets[name]) {
+		case 0:
+			continue
+		case all:
+		default:
+			gated[name] = sets[name]
+		}
+		built = append(built, name)
+	}
+	return built, gated
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildFilesKeepsGatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.go":              "package app\n",
+		"app_linux.go":        "package app\n\nimport \"os/user\"\n",
+		"app_windows.go":      "package app\n\nimport \"net\"\n",
+		"app_windows_test.go": "package app\n",
+		"gen.go":              "//go:build ignore\n\npackage main\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved, sets, flag := build.Default, constraintSets, *platformsFlag
+	t.Cleanup(func() { build.Default, constraintSets, *platformsFlag = saved, sets, flag })
+	*platformsFlag = "linux/amd64,windows/amd64"
+	parsed, err := parsePlatforms(*platformsFlag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usePlatforms(parsed)
+
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, gated := buildFiles(pkg)
+	if got, want := strings.Join(names, " "), "app.go app_linux.go app_windows.go"; got != want {
+		t.Errorf("files %s, want %s", got, want)
+	}
+	for name, want := range map[string]string{"app.go": "", "app_linux.go": "linux/amd64", "app_windows.go": "windows/amd64"} {
+		if got := strings.Join(gated[name], ","); got != want {
+			t.Errorf("%s is built by %q of the sets, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadFocus reads the packages listed one per line in a focus file. Empty
+// lines and lines starting with # are skipped.
+func loadFocus(name string) (map[string]bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open focus file: %s", err)
+	}
+	defer f.Close()
+
+	focus := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			focus[line] = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read focus file: %s", err)
+	}
+	return focus, nil
+}
+
+// focus reduces the graph to the given packages and the edges among them.
+// With neighbours, the packages they import or are imported by directly are
+// kept as well, along with the edges from and to the focus packages.
+func (g *graph) focus(focus map[string]bool, neighbours bool) {
+	found := make(map[string]bool)
+	for _, n := range g.Nodes {
+		found[n.ID] = true
+	}
+	for id := range focus {
+		if !found[id] {
+			logger.Warn("focus package is not in the graph", "pkg", id)
+		}
+	}
+
+	keep := make(map[string]bool)
+	for id := range focus {
+		keep[id] = true
+	}
+	if neighbours {
+		for _, e := range g.Edges {
+			if focus[e.From] {
+				keep[e.To] = true
+			}
+			if focus[e.To] {
+				keep[e.From] = true
+			}
+		}
+	}
+
+	var nodes []*node
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			nodes = append(nodes, n)
+		}
+	}
+	var edges []*edge
+	for _, e := range g.Edges {
+		if keep[e.From] && keep[e.To] && (focus[e.From] || focus[e.To] || !neighbours) {
+			edges = append(edges, e)
+		}
+	}
+	g.Nodes, g.Edges = nodes, edges
+}
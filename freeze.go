@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// manifest lists the dependencies allowed by check -allowed: the external
+// modules, and the imports between the packages of the own module as
+// "from -> to"
+type manifest struct {
+	modules map[string]bool
+	imports map[string]bool
+}
+
+// freeze returns the manifest allowing exactly the dependencies of the graph
+func (g *graph) freeze() *manifest {
+	m := &manifest{modules: stringSet(g.externalModules()), imports: make(map[string]bool)}
+	for _, imp := range g.internalImports() {
+		m.imports[imp] = true
+	}
+	return m
+}
+
+// internalImports returns the imports between packages of the own module,
+// sorted and written as "from -> to". Without a known own module there are
+// none, as every package without module data would count as own.
+func (g *graph) internalImports() []string {
+	own := g.ownModule()
+	if own == "" {
+		return nil
+	}
+	internal := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Module == own && !n.Stdlib && !n.Ghost && n.Error == "" {
+			internal[n.ID] = true
+		}
+	}
+	seen := make(map[string]bool)
+	var imports []string
+	for _, e := range g.Edges {
+		imp := e.From + " -> " + e.To
+		if internal[e.From] && internal[e.To] && !seen[imp] {
+			seen[imp] = true
+			imports = append(imports, imp)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// printFreeze prints the manifest of the graph, to be checked in and given
+// to check -allowed
+func printFreeze(g *graph) error {
+	m := g.freeze()
+	fmt.Printf("# dependencies allowed for %s, from godepgraph freeze\n", g.ownModule())
+	for _, mod := range sortedKeys(m.modules) {
+		fmt.Printf("module %s\n", mod)
+	}
+	for _, imp := range sortedKeys(m.imports) {
+		fmt.Printf("import %s\n", imp)
+	}
+	return nil
+}
+
+// loadManifest reads a manifest written by freeze
+func loadManifest(name string) (*manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %s", err)
+	}
+	defer f.Close()
+
+	m := &manifest{modules: make(map[string]bool), imports: make(map[string]bool)}
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kind, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		switch {
+		case kind == "module" && value != "":
+			m.modules[value] = true
+		case kind == "import" && strings.Contains(value, " -> "):
+			m.imports[value] = true
+		default:
+			return nil, fmt.Errorf("%s:%d: expected module <path> or import <from> -> <to>", name, n)
+		}
+	}
+	return m, s.Err()
+}
+
+// unfrozen returns the external modules and internal imports of the graph
+// the manifest does not allow, and the number of allowed ones no longer used
+func (m *manifest) unfrozen(g *graph) (modules, imports []string, unused int) {
+	current := g.freeze()
+	for _, mod := range sortedKeys(current.modules) {
+		if !m.modules[mod] {
+			modules = append(modules, mod)
+		}
+	}
+	for _, imp := range sortedKeys(current.imports) {
+		if !m.imports[imp] {
+			imports = append(imports, imp)
+		}
+	}
+	for mod := range m.modules {
+		if !current.modules[mod] {
+			unused++
+		}
+	}
+	for imp := range m.imports {
+		if !current.imports[imp] {
+			unused++
+		}
+	}
+	return modules, imports, unused
+}
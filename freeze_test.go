@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInternalImports(t *testing.T) {
+	tests := []struct {
+		name   string
+		module string
+		want   string
+	}{
+		{"own module", "example.com/app", "example.com/app -> example.com/app/store"},
+		{"no module data", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &graph{
+				Roots: []string{"example.com/app"},
+				Nodes: []*node{
+					{ID: "example.com/app", Module: tt.module},
+					{ID: "example.com/app/store", Module: tt.module},
+					{ID: "fmt", Stdlib: true},
+				},
+				Edges: []*edge{
+					{From: "example.com/app", To: "example.com/app/store"},
+					{From: "example.com/app", To: "fmt"},
+				},
+			}
+			if got := strings.Join(g.internalImports(), ", "); got != tt.want {
+				t.Errorf("internal imports %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitTopLevels caches the work tree a directory belongs to, "" outside of git
+var gitTopLevels = make(map[string]string)
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %s %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// gitTopLevel returns the root of the git work tree holding dir
+func gitTopLevel(dir string) string {
+	if top, ok := gitTopLevels[dir]; ok {
+		return top
+	}
+	top := ""
+	if output, err := git(dir, "rev-parse", "--show-toplevel"); err == nil {
+		top = filepath.Clean(strings.TrimSpace(string(output)))
+	}
+	gitTopLevels[dir] = top
+	return top
+}
+
+// gitRelDir returns the directory of a package relative to its work tree
+func gitRelDir(top, dir string) string {
+	rel, err := filepath.Rel(top, dir)
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// loadChurn counts the commits of the last days days that changed a file of
+// each visible package, not counting its subpackages
+func loadChurn(days int) (map[string]int, error) {
+	perTop := make(map[string]map[string]int)
+	churn := make(map[string]int)
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot {
+			continue
+		}
+		top := gitTopLevel(pkg.Dir)
+		if top == "" {
+			continue
+		}
+		perDir, ok := perTop[top]
+		if !ok {
+			var err error
+			if perDir, err = commitsPerDir(top, days); err != nil {
+				return nil, fmt.Errorf("failed to read history of %s: %s", top, err)
+			}
+			perTop[top] = perDir
+		}
+		churn[pkgName] = perDir[gitRelDir(top, pkg.Dir)]
+	}
+	return churn, nil
+}
+
+// commitsPerDir counts the commits of the last days days per directory of a
+// work tree
+func commitsPerDir(top string, days int) (map[string]int, error) {
+	output, err := git(top, "log", fmt.Sprintf("--since=%d.days", days), "--format=%x00", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	perDir := make(map[string]int)
+	dirs := make(map[string]bool)
+	flush := func() {
+		for dir := range dirs {
+			perDir[dir]++
+		}
+		dirs = make(map[string]bool)
+	}
+	s := bufio.NewScanner(bytes.NewReader(output))
+	for s.Scan() {
+		switch line := s.Text(); line {
+		case "\x00":
+			flush()
+		case "":
+		default:
+			dirs[path.Dir(line)] = true
+		}
+	}
+	flush()
+	return perDir, s.Err()
+}
+
+// churnAttrs fills the node from white to red by its share of the commits of
+// the most changed package
+func churnAttrs(pkgName string, churn map[string]int) attrs {
+	commits, ok := churn[pkgName]
+	if !ok {
+		return nil
+	}
+	max := 1
+	for _, v := range churn {
+		if v > max {
+			max = v
+		}
+	}
+	return attrs{
+		"label":     fmt.Sprintf("(%d commits)", commits),
+		"fillcolor": heatColor(float64(commits) / float64(max)),
+		"tooltip":   fmt.Sprintf("%d commits in the last %d days", commits, *churnDays),
+	}
+}
+
+// heatColor returns a color from white at 0 to red at 1
+func heatColor(scale float64) string {
+	return blend(scale, 255, 55, 55)
+}
+
+// blend returns the color scale of the way from white to the given one
+func blend(scale float64, r, g, b int) string {
+	mix := func(c int) int {
+		return 255 - int(scale*float64(255-c))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", mix(r), mix(g), mix(b))
+}
+
+// loadAges returns when the files of each visible package were last changed,
+// by the last commit touching them or, outside of git, their modification
+// time. The standard library is left out.
+func loadAges() (map[string]time.Time, error) {
+	ages := make(map[string]time.Time)
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot {
+			continue
+		}
+		files := append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...)
+		var changed time.Time
+		if gitTopLevel(pkg.Dir) != "" {
+			output, err := git(pkg.Dir, append([]string{"log", "-1", "--format=%ct", "--"}, files...)...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read history of %s: %s", pkgName, err)
+			}
+			if sec, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); err == nil {
+				changed = time.Unix(sec, 0)
+			}
+		}
+		if changed.IsZero() {
+			// not in git, or not committed yet
+			for _, name := range files {
+				if fi, err := os.Stat(filepath.Join(pkg.Dir, name)); err == nil && fi.ModTime().After(changed) {
+					changed = fi.ModTime()
+				}
+			}
+		}
+		if !changed.IsZero() {
+			ages[pkgName] = changed
+		}
+	}
+	return ages, nil
+}
+
+// ageAttrs fills the node from white to brown by its age relative to the
+// oldest package
+func ageAttrs(pkgName string, ages map[string]time.Time) attrs {
+	changed, ok := ages[pkgName]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	var max time.Duration = 1
+	for _, t := range ages {
+		if age := now.Sub(t); age > max {
+			max = age
+		}
+	}
+	age := now.Sub(changed)
+	return attrs{
+		"label":     fmt.Sprintf("(changed %d days ago)", int(age.Hours()/24)),
+		"fillcolor": blend(float64(age)/float64(max), 139, 115, 85),
+		"tooltip":   "last changed " + changed.Format("2006-01-02"),
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// isGlob reports whether a filter value is a glob pattern rather than a
+// plain import path or prefix
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// matchGlob reports whether an import path matches a glob pattern. The
+// pattern is matched element by element with path.Match, and an element **
+// matches any number of path elements, including none.
+func matchGlob(pattern, importPath string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(importPath, "/"))
+}
+
+func matchElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], elems[0]); err != nil || !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+// matchesPrefix reports whether an import path starts with prefix, or
+// matches it as a whole if it is a glob pattern
+func matchesPrefix(importPath, prefix string) bool {
+	if isGlob(prefix) {
+		return matchGlob(prefix, importPath)
+	}
+	return strings.HasPrefix(importPath, prefix)
+}
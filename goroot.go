@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+)
+
+// useGoroot makes dir the root of the standard library, for projects built
+// with a custom or vendored toolchain. The go command that go/build runs in
+// module mode is pointed there as well.
+func useGoroot(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid -goroot: %s", err)
+	}
+	if fi, err := os.Stat(filepath.Join(abs, "src")); err != nil || !fi.IsDir() {
+		return fmt.Errorf("-goroot %s has no src directory", dir)
+	}
+	build.Default.GOROOT = abs
+	return os.Setenv("GOROOT", abs)
+}
+
+// markGoroot classifies a package found beneath the src directory of the
+// standard library root as part of it. go/build only does so for the
+// GOROOT it was configured with, and packages of a replacement toolchain
+// may also be found by other ways, like a GOPATH entry holding it.
+func markGoroot(pkg *build.Package) {
+	if !pkg.Goroot && pkg.Dir != "" && hasPathPrefix(pkg.Dir, filepath.Join(build.Default.GOROOT, "src")) {
+		pkg.Goroot = true
+	}
+}
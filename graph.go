@@ -0,0 +1,728 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"sort"
+	"time"
+)
+
+// graph is the dependency graph as it is rendered and exported. Merged
+// graphs carry the namespace of their origin on every node and edge.
+type graph struct {
+	Namespace string   `json:"namespace"`
+	Roots     []string `json:"roots,omitempty"`
+	Nodes     []*node  `json:"nodes"`
+	Edges     []*edge  `json:"edges"`
+
+	// Simplifications tells what the filters and views left out
+	Simplifications []string `json:"simplifications,omitempty"`
+}
+
+type node struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace,omitempty"`
+	Module    string `json:"module,omitempty"`
+	Stdlib    bool   `json:"stdlib,omitempty"`
+	Cgo       bool   `json:"cgo,omitempty"`
+	// Dependency is "root", "direct" for packages a root imports, or
+	// "transitive" for everything pulled in further down
+	Dependency string `json:"dependency,omitempty"`
+	// Error is why the package failed to resolve
+	Error string `json:"error,omitempty"`
+	// Ghost marks a filtered package, shown only because something imports it
+	Ghost bool `json:"ghost,omitempty"`
+	// Test marks the external test package of the package it is named after
+	Test bool `json:"test,omitempty"`
+	// Constraints lists the -constraints or -platforms sets under which a
+	// package is reached, if not all of them
+	Constraints []string `json:"constraints,omitempty"`
+	// Exported is the number of exported identifiers, with -exported
+	Exported int `json:"exported,omitempty"`
+	// Files and Lines count the Go files of the package and their lines
+	// that are not blank, tests left out
+	Files int `json:"files,omitempty"`
+	Lines int `json:"lines,omitempty"`
+	// Symbol marks an identifier of the package named by Group, see -expand
+	Symbol bool `json:"symbol,omitempty"`
+	// Group is the user-defined group of the package, see -groups
+	Group string `json:"group,omitempty"`
+	Color string `json:"color"`
+	Attrs attrs  `json:"attrs,omitempty"`
+	// Description and URL are the curated context of the package, see
+	// -metadata
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	// Category is the class of the package in the user's taxonomy, see
+	// -classify
+	Category string `json:"category,omitempty"`
+	// Metrics measures the coupling of the package, in snapshots
+	Metrics *nodeMetrics `json:"metrics,omitempty"`
+}
+
+type edge struct {
+	Namespace string `json:"namespace,omitempty"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	// Test marks an import made only by tests
+	Test bool `json:"test,omitempty"`
+	// Blank marks an import made only for its side effects
+	Blank bool `json:"blank,omitempty"`
+	// Constraints lists the -constraints sets making an import that not all
+	// of them make
+	Constraints []string `json:"constraints,omitempty"`
+	// Sources lists the import declarations making the edge, with
+	// -provenance
+	Sources []source `json:"sources,omitempty"`
+	// Symbols is the number of exported identifiers used across the edge,
+	// with -symbols
+	Symbols int   `json:"symbols,omitempty"`
+	Attrs   attrs `json:"attrs,omitempty"`
+}
+
+// namespaceOf returns the namespace of a node or edge, which defaults to the
+// one of the graph
+func (g *graph) namespaceOf(namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return g.Namespace
+}
+
+// namespaces returns the distinct namespaces of all nodes in order of
+// appearance
+func (g *graph) namespaces() []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, n := range g.Nodes {
+		if namespace := g.namespaceOf(n.Namespace); !seen[namespace] {
+			seen[namespace] = true
+			list = append(list, namespace)
+		}
+	}
+	if len(list) == 0 {
+		list = append(list, g.Namespace)
+	}
+	return list
+}
+
+// ownModule returns the module of the first root package
+func (g *graph) ownModule() string {
+	if len(g.Roots) == 0 {
+		return ""
+	}
+	for _, n := range g.Nodes {
+		if n.ID == g.Roots[0] {
+			return n.Module
+		}
+	}
+	return ""
+}
+
+// externalModules returns the modules of all nodes other than the root's own
+// and the standard library
+func (g *graph) externalModules() []string {
+	own := g.ownModule()
+	seen := make(map[string]bool)
+	var mods []string
+	for _, n := range g.Nodes {
+		if n.Stdlib || n.Module == "" || n.Module == own || seen[n.Module] {
+			continue
+		}
+		seen[n.Module] = true
+		mods = append(mods, n.Module)
+	}
+	sort.Strings(mods)
+	return mods
+}
+
+// buildGraph assembles the graph of all visible packages, annotated by the
+// overlays selected on the command line
+func buildGraph() (*graph, error) {
+	var err error
+	var complexity map[string]int
+	var complexityMax int
+	if overlayEnabled("complexity") {
+		if complexity, err = loadComplexity(*gocycloFile); err != nil {
+			return nil, err
+		}
+		complexityMax = maxComplexity(complexity)
+	}
+
+	var exported map[string]int
+	if overlayEnabled("exported") {
+		if exported, err = loadExported(); err != nil {
+			return nil, err
+		}
+	}
+
+	var usage symbolUsage
+	if overlayEnabled("symbols") || *expandPackages != "" {
+		if usage, err = loadSymbolUsage(); err != nil {
+			return nil, err
+		}
+	}
+
+	var binarySizes map[string]int64
+	if overlayEnabled("binary") {
+		if binarySizes, err = loadBinarySizes(*binaryFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var times *buildTimes
+	if overlayEnabled("actiongraph") {
+		if times, err = loadBuildTimes(*actionGraphFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var churn map[string]int
+	if overlayEnabled("churn") {
+		if churn, err = loadChurn(*churnDays); err != nil {
+			return nil, err
+		}
+	}
+
+	var ages map[string]time.Time
+	if overlayEnabled("age") {
+		if ages, err = loadAges(); err != nil {
+			return nil, err
+		}
+	}
+
+	var owners *ownership
+	if overlayEnabled("authors") {
+		if owners, err = loadOwnership(*teamsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var inconsistent map[string]map[string][]string
+	if overlayEnabled("aliases") {
+		inconsistent = inconsistentAliases()
+	}
+
+	var lags map[string]*lag
+	if overlayEnabled("outdated") {
+		if lags, err = loadOutdated(); err != nil {
+			return nil, err
+		}
+	}
+
+	dependency := dependencies()
+	g := &graph{Namespace: graphNamespace(), Roots: rootPackages}
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+
+		dep, ok := dependency[pkgName]
+		if !ok {
+			dep = "transitive"
+		}
+		extra := attrs{}
+		if dep == "direct" {
+			// a double border sets direct dependencies apart
+			extra["peripheries"] = "2"
+		}
+		if complexity != nil {
+			extra.add(complexityAttrs(pkgName, complexity, complexityMax))
+		}
+		files, lines, err := packageSize(pkg)
+		if err != nil {
+			logger.Warn("failed to measure package", "pkg", pkgName, "err", err)
+		}
+		if overlayEnabled("size") {
+			extra.add(sizeAttrs(files, lines))
+		}
+		if exported != nil {
+			extra.add(exportedAttrs(pkgName, exported))
+		}
+		if binarySizes != nil {
+			extra.add(binarySizeAttrs(pkgName, binarySizes))
+		}
+		if times != nil {
+			extra.add(times.nodeAttrs(pkgName))
+		}
+		if churn != nil {
+			extra.add(churnAttrs(pkgName, churn))
+		}
+		if ages != nil {
+			extra.add(ageAttrs(pkgName, ages))
+		}
+		if owners != nil {
+			extra.add(owners.nodeAttrs(pkgName))
+		}
+		if lags != nil {
+			extra.add(outdatedAttrs(moduleOf(pkg), lags))
+		}
+		color := nodeColor(pkg)
+		if *allMains && dep == "root" {
+			color = "lightsalmon"
+		}
+		d := directives(pkg)
+		if group, ok := d["group"]; ok {
+			directiveGroups[pkgName] = group
+		}
+		if c, ok := d["color"]; ok {
+			color = c
+		}
+		g.Nodes = append(g.Nodes, &node{
+			ID:         pkgName,
+			Module:     moduleOf(pkg),
+			Stdlib:     pkg.Goroot,
+			Cgo:        len(pkg.CgoFiles) > 0,
+			Dependency: dep,
+			Exported:   exported[pkgName],
+			Files:      files,
+			Lines:      lines,
+			Group:      directiveGroups[pkgName],
+			Color:      color,
+			Attrs:      extra,
+		})
+
+		for _, imp := range visibleImports(pkg) {
+			edgeExtra := attrs{}
+			if times != nil {
+				edgeExtra.add(times.edgeAttrs(pkgName, imp))
+			}
+			if overlayEnabled("aliases") {
+				edgeExtra.add(aliasEdgeAttrs(pkgName, imp, inconsistent))
+			}
+			var symbols int
+			if overlayEnabled("symbols") {
+				symbols = len(usage[[2]string{pkgName, imp}])
+				edgeExtra.add(usage.edgeAttrs(pkgName, imp))
+			}
+			constraints := importConstraints(pkgName, imp)
+			if constraints != nil {
+				edgeExtra.add(constraintEdgeAttrs(constraints))
+			}
+			test := isTestImport(pkg, imp)
+			if test {
+				edgeExtra["style"] = "dashed"
+			}
+			blank := isBlankImport(pkg, imp)
+			if blank {
+				edgeExtra["style"] = "dotted"
+				edgeExtra["arrowhead"] = "odot"
+				edgeExtra["tooltip"] = "blank import"
+			}
+			g.Edges = append(g.Edges, &edge{From: pkgName, To: imp, Test: test, Blank: blank, Constraints: constraints, Symbols: symbols, Attrs: edgeExtra})
+		}
+	}
+	if *withTests {
+		g.addExternalTests()
+	}
+	if overlayEnabled("provenance") {
+		g.addProvenance()
+	}
+	if len(constraintSets) > 0 {
+		g.markConstraints()
+	}
+	if overlayEnabled("deprecated") {
+		g.markDeprecated()
+	}
+	g.addErrors()
+	if overlayEnabled("ghosts") {
+		g.addGhosts()
+	}
+	if len(omitted) > 0 {
+		g.note("filters hid %d %s", len(omitted), choose(len(omitted) == 1, "package", "packages"))
+	}
+	switch *granularity {
+	case "package":
+	case "file":
+		if err := g.splitFiles(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown -granularity %q, must be package or file", *granularity)
+	}
+	if err := g.applyViews(); err != nil {
+		return nil, err
+	}
+	if *expandPackages != "" {
+		for _, pkgName := range sanitizeCSV(*expandPackages) {
+			g.expand(pkgName, usage)
+		}
+	}
+	return g, nil
+}
+
+// dependencies maps the roots to "root" and the packages they import to
+// "direct". Everything else is a transitive dependency.
+func dependencies() map[string]string {
+	dependency := make(map[string]string)
+	for _, root := range rootPackages {
+		for _, imp := range pkgs[root].Imports {
+			dependency[imp] = "direct"
+		}
+	}
+	for _, root := range rootPackages {
+		dependency[root] = "root"
+	}
+	return dependency
+}
+
+// graphNamespace returns the namespace selected by -namespace: the base
+// path by default, and none for "none"
+func graphNamespace() string {
+	switch *namespaceFlag {
+	case "":
+		return basePath
+	case "none":
+		return ""
+	}
+	return *namespaceFlag
+}
+
+// applyViews reduces and arranges the graph as selected on the command line.
+// Unlike the overlays, views only need the graph, so they apply to loaded
+// graphs as well.
+func (g *graph) applyViews() error {
+	if *metadataFile != "" {
+		metadata, err := loadMetadata(*metadataFile)
+		if err != nil {
+			return err
+		}
+		g.addMetadata(metadata)
+	}
+	if *cutEdgesFlag != "" {
+		before := g.counts()
+		rules, err := parseCutRules(*cutEdgesFlag)
+		if err != nil {
+			return err
+		}
+		g.cutEdges(rules)
+		g.noteHidden("-cut-edges", before)
+	}
+	if *fanIn {
+		g.fanInBorders()
+	}
+	if *renameFile != "" {
+		before := g.counts()
+		r, err := loadRenames(*renameFile)
+		if err != nil {
+			return err
+		}
+		g.migrationView(r)
+		g.noteHidden("-rename", before)
+	}
+	if *setView != "" {
+		before := g.counts()
+		if err := g.applySet(*setView); err != nil {
+			return err
+		}
+		g.noteHidden("-set", before)
+	}
+	if *reachableToFlag != "" {
+		before := g.counts()
+		g.reachableTo(sanitizeCSV(*reachableToFlag))
+		g.noteHidden("-reachable-to", before)
+	}
+	if *focusFile != "" {
+		before := g.counts()
+		focus, err := loadFocus(*focusFile)
+		if err != nil {
+			return err
+		}
+		g.focus(focus, *focusNeighbours)
+		g.noteHidden("-focus-file", before)
+	}
+	if *sampleSize > 0 {
+		before := g.counts()
+		g.sample(*sampleSize)
+		g.noteHidden("-sample", before)
+	}
+	if *contractChains {
+		before := g.counts()
+		g.contractChains()
+		g.noteHidden("-contract-chains", before)
+	}
+	if *groupsFile != "" {
+		gr, err := loadGroups(*groupsFile)
+		if err != nil {
+			return err
+		}
+		g.assignGroups(gr)
+	}
+	if *classifyFile != "" || *classifyExec != "" {
+		c := &classifier{}
+		if *classifyFile != "" {
+			var err error
+			if c, err = loadClassifier(*classifyFile); err != nil {
+				return err
+			}
+		}
+		var hook map[string]string
+		if *classifyExec != "" {
+			var err error
+			if hook, err = runClassifyHook(*classifyExec, g); err != nil {
+				return err
+			}
+		}
+		g.classify(c, hook)
+	}
+	if *collapseGroups {
+		g.collapseGroups()
+	}
+	return nil
+}
+
+// addErrors adds a red node for every package that failed to resolve, with
+// the reason in its tooltip, and connects it to its importers. Packages that
+// failed for the same cause are grouped by it.
+func (g *graph) addErrors() {
+	// packages failing for the same cause are drawn in one box
+	errorGroup := make(map[string]string)
+	causes, members := failureCauses()
+	for _, cause := range causes {
+		if len(members[cause]) > 1 {
+			for _, name := range members[cause] {
+				errorGroup[name] = cause
+			}
+		}
+	}
+	var names []string
+	for name := range unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.Nodes = append(g.Nodes, &node{
+			ID:    name,
+			Error: unresolved[name].Error(),
+			Group: errorGroup[name],
+			Color: "red",
+			Attrs: attrs{"tooltip": unresolved[name].Error()},
+		})
+	}
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		for _, imp := range imports(pkg) {
+			if pkg.Goroot {
+				imp = stdlibImport(imp)
+			}
+			if unresolved[imp] != nil {
+				g.Edges = append(g.Edges, &edge{From: pkgName, To: imp, Attrs: attrs{"color": "red"}})
+			}
+		}
+	}
+}
+
+// addGhosts adds a small grey node for every filtered package that a node
+// imports, so that filtering hides the details of a dependency but never the
+// dependency itself
+func (g *graph) addGhosts() {
+	seen := make(map[string]bool)
+	for _, n := range g.Nodes {
+		seen[n.ID] = true
+	}
+	for _, pkgName := range visiblePackages() {
+		for _, imp := range hiddenImports(pkgs[pkgName]) {
+			if !seen[imp] {
+				seen[imp] = true
+				g.Nodes = append(g.Nodes, &node{
+					ID:     imp,
+					Stdlib: isStdlib(imp),
+					Ghost:  true,
+					Color:  "lightgrey",
+					Attrs:  attrs{"fontsize": "10", "fontcolor": "grey40", "tooltip": "filtered"},
+				})
+			}
+			g.Edges = append(g.Edges, &edge{
+				From:  pkgName,
+				To:    imp,
+				Attrs: attrs{"style": "dashed", "color": "grey"},
+			})
+		}
+	}
+}
+
+// visiblePackages returns the import paths of all loaded packages that are
+// not ignored, in sorted order.
+func visiblePackages() []string {
+	var names []string
+	for path, pkg := range pkgs {
+		if !isIgnored(pkg) {
+			names = append(names, path)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// visibleImports returns the imports of pkg that end up in the graph.
+// Imports of stdlib packages are only rendered with -stdlib-edges, test
+// imports only with -t.
+func visibleImports(pkg *build.Package) []string {
+	if pkg.Goroot && !*stdlibEdges {
+		return nil
+	}
+	var imps []string
+	for _, imp := range imports(pkg) {
+		if pkg.Goroot {
+			imp = stdlibImport(imp)
+		}
+		if impPkg := pkgs[imp]; impPkg != nil && !isIgnored(impPkg) {
+			imps = append(imps, imp)
+		}
+	}
+	return imps
+}
+
+// hiddenImports returns the imports of pkg that were filtered out of the
+// graph. Packages that failed to resolve are not among them.
+func hiddenImports(pkg *build.Package) []string {
+	if pkg.Goroot && !*stdlibEdges {
+		return nil
+	}
+	var imps []string
+	for _, imp := range imports(pkg) {
+		if pkg.Goroot {
+			imp = stdlibImport(imp)
+		}
+		if imp == "C" || unresolved[imp] != nil {
+			continue
+		}
+		if impPkg := pkgs[imp]; impPkg == nil || isIgnored(impPkg) {
+			imps = append(imps, imp)
+		}
+	}
+	return imps
+}
+
+// cycles returns the strongly connected components of the visible graph
+// that contain more than one package
+func cycles() [][]string {
+	return stronglyConnected(visiblePackages(), func(v string) []string {
+		return visibleImports(pkgs[v])
+	})
+}
+
+// depth returns the length of the longest import chain starting at the
+// given package, counting packages. Packages on a cycle are only counted
+// once per chain.
+func depth(from string) int {
+	if _, ok := pkgs[from]; !ok {
+		return 0
+	}
+	return longestChain(from, func(v string) []string {
+		return visibleImports(pkgs[v])
+	})
+}
+
+// imports returns the edges of the graph as a function from a node to its
+// imports, leaving out filtered and unresolved packages, so that it matches
+// the imports of the scanned packages
+func (g *graph) imports() func(string) []string {
+	skip := make(map[string]bool)
+	for _, n := range g.Nodes {
+		skip[n.ID] = n.Ghost || n.Error != ""
+	}
+	out := make(map[string][]string)
+	for _, e := range g.Edges {
+		if !skip[e.To] {
+			out[e.From] = append(out[e.From], e.To)
+		}
+	}
+	return func(v string) []string { return out[v] }
+}
+
+// cycles returns the import cycles of the graph, see cycles
+func (g *graph) cycles() [][]string {
+	var ids []string
+	for _, n := range g.Nodes {
+		if !n.Ghost && n.Error == "" {
+			ids = append(ids, n.ID)
+		}
+	}
+	sort.Strings(ids)
+	return stronglyConnected(ids, g.imports())
+}
+
+// depth returns the longest import chain of the graph from a node, see depth
+func (g *graph) depth(from string) int {
+	for _, n := range g.Nodes {
+		if n.ID == from {
+			return longestChain(from, g.imports())
+		}
+	}
+	return 0
+}
+
+// stronglyConnected returns the strongly connected components with more
+// than one member of the graph given by its nodes and their successors,
+// using Tarjan's algorithm.
+func stronglyConnected(nodes []string, next func(string) []string) [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range next(v) {
+			if _, seen := index[w]; !seen {
+				connect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			if len(scc) > 1 {
+				sort.Strings(scc)
+				sccs = append(sccs, scc)
+			}
+		}
+	}
+
+	for _, v := range nodes {
+		if _, seen := index[v]; !seen {
+			connect(v)
+		}
+	}
+	return sccs
+}
+
+// longestChain returns the number of nodes on the longest path from the
+// given one, counting nodes on a cycle once per path
+func longestChain(from string, next func(string) []string) int {
+	memo := make(map[string]int)
+	var walk func(v string) int
+	walk = func(v string) int {
+		if d, ok := memo[v]; ok {
+			return d
+		}
+		memo[v] = 0 // breaks cycles
+		max := 0
+		for _, w := range next(v) {
+			if d := walk(w); d > max {
+				max = d
+			}
+		}
+		memo[v] = max + 1
+		return memo[v]
+	}
+	return walk(from)
+}
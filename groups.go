@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// groups maps import path prefixes to the names of user-defined groups
+type groups map[string]string
+
+// loadGroups reads a file of "prefix = group" lines. The special prefix std
+// stands for the standard library.
+func loadGroups(name string) (groups, error) {
+	config, err := readConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return groups(config), nil
+}
+
+// of returns the group of the longest prefix matching the import path, or ""
+func (gr groups) of(id string, stdlib bool) string {
+	if stdlib {
+		if group, ok := gr["std"]; ok {
+			return group
+		}
+	}
+	var prefixes []string
+	for prefix := range gr {
+		if prefix != "std" && strings.HasPrefix(id, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return ""
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return gr[prefixes[0]]
+}
+
+// assignGroups sets the group of every node, unless a //godepgraph:group
+// directive of the package already did or the package failed to resolve
+// along with others for the same cause
+func (g *graph) assignGroups(gr groups) {
+	for _, n := range g.Nodes {
+		if group, ok := directiveGroups[n.ID]; ok {
+			n.Group = group
+			continue
+		}
+		if n.Error != "" && n.Group != "" {
+			// the cause of failure of the package
+			continue
+		}
+		n.Group = gr.of(n.ID, n.Stdlib)
+	}
+}
+
+// collapseGroups replaces the packages of every group by a single node named
+// after the group, for a diagram of the components alone. The imports
+// between two groups, or between a group and a package outside of any, turn
+// into one edge labelled with their number; imports within a group are
+// dropped.
+func (g *graph) collapseGroups() {
+	groupOf := make(map[string]string)
+	var names []string
+	members := make(map[string][]string)
+	var nodes []*node
+	for _, n := range g.Nodes {
+		if n.Group == "" {
+			nodes = append(nodes, n)
+			continue
+		}
+		groupOf[n.ID] = n.Group
+		if members[n.Group] == nil {
+			names = append(names, n.Group)
+		}
+		members[n.Group] = append(members[n.Group], n.ID)
+	}
+	for _, name := range names {
+		nodes = append(nodes, &node{
+			ID:    name,
+			Color: "white",
+			Attrs: attrs{
+				"shape":   "box",
+				"style":   "filled,rounded",
+				"color":   "black",
+				"label":   fmt.Sprintf("(%d %s)", len(members[name]), choose(len(members[name]) == 1, "package", "packages")),
+				"tooltip": strings.Join(members[name], "\n"),
+			},
+		})
+	}
+
+	end := func(id string) string {
+		if group, ok := groupOf[id]; ok {
+			return group
+		}
+		return id
+	}
+	var edges []*edge
+	byPair := make(map[[2]string]*edge)
+	count := make(map[*edge]int)
+	for _, e := range g.Edges {
+		from, to := end(e.From), end(e.To)
+		if from == e.From && to == e.To {
+			edges = append(edges, e)
+			continue
+		}
+		if from == to {
+			continue
+		}
+		pair := [2]string{from, to}
+		if byPair[pair] == nil {
+			byPair[pair] = &edge{Namespace: e.Namespace, From: from, To: to, Attrs: attrs{}}
+			edges = append(edges, byPair[pair])
+		}
+		count[byPair[pair]]++
+	}
+	aggregated := 0
+	for e, n := range count {
+		e.Attrs["label"] = fmt.Sprintf("%d", n)
+		aggregated += n
+	}
+	packages := len(g.Nodes) - len(nodes) + len(names)
+	within := len(g.Edges) - len(edges) - aggregated + len(count)
+	g.note("-collapse-groups collapsed %d packages into %d %s, aggregated %d imports into %d %s and hid %d %s within groups",
+		packages, len(names), choose(len(names) == 1, "group", "groups"),
+		aggregated, len(count), choose(len(count) == 1, "edge", "edges"),
+		within, choose(within == 1, "import", "imports"))
+	g.Nodes, g.Edges = nodes, edges
+}
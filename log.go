@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// exit codes, for scripts to branch on the outcome of the analysis
+const (
+	exitFailure    = 1  // the run failed, there is no output
+	exitCycles     = 2  // the graph contains import cycles
+	exitViolations = 3  // a check did not pass
+	exitUnresolved = 4  // some packages could not be resolved
+	exitUsage      = 64 // the command line is wrong, like EX_USAGE of sysexits.h
+)
+
+// exitStatus returns the exit code of a run that produced its output: 0 if
+// the analysis came out clean, otherwise the code of the most severe finding
+func exitStatus(violations, cyclic bool) int {
+	switch {
+	case len(unresolved) > 0:
+		return exitUnresolved
+	case violations:
+		return exitViolations
+	case cyclic:
+		return exitCycles
+	}
+	return 0
+}
+
+// cyclic reports whether the import cycles decide the exit status: not
+// after check and conform, which judge the cycles by their budget and
+// model, and not for cycles through a package with an active -exemptions
+// entry
+func cyclic(command string, cycs [][]string) (bool, error) {
+	if command == "check" || command == "conform" || len(cycs) == 0 {
+		return false, nil
+	}
+	exemptions := make(map[string]*exemption)
+	if *exemptionsFile != "" {
+		var err error
+		if exemptions, err = loadExemptions(*exemptionsFile); err != nil {
+			return false, err
+		}
+	}
+	for _, c := range cycs {
+		if exempt(exemptions, c...) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// logOutput receives the diagnostics, and what graphviz dot complains about
+var logOutput io.Writer = os.Stderr
+
+// setupLogging configures the level, format and destination of the
+// diagnostics: warnings and errors by default, less with -q, more with -v
+// and -vv, and one JSON object per line with -log-json. They go to stderr,
+// or with -log-file to a file or an open file descriptor, so that nothing
+// but the output of the run ever shares a stream with them.
+func setupLogging() {
+	level := slog.LevelWarn
+	switch {
+	case *veryVerbose:
+		level = slog.LevelDebug
+	case *verbose:
+		level = slog.LevelInfo
+	case *quiet:
+		level = slog.LevelError
+	}
+	if *logFile != "" {
+		w, err := openLogFile(*logFile)
+		if err != nil {
+			fatal(err)
+		}
+		logOutput = w
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if *logJSON {
+		logger = slog.New(slog.NewJSONHandler(logOutput, opts))
+	} else {
+		logger = slog.New(slog.NewTextHandler(logOutput, opts))
+	}
+}
+
+// openLogFile opens the destination of -log-file: a number is a file
+// descriptor the caller opened, like 3 for 3>diagnostics.log, anything else
+// a file to append to
+func openLogFile(name string) (io.Writer, error) {
+	if fd, err := strconv.Atoi(name); err == nil {
+		f := os.NewFile(uintptr(fd), "fd "+name)
+		if f == nil {
+			return nil, fmt.Errorf("invalid file descriptor %d for -log-file", fd)
+		}
+		if _, err := f.Stat(); err != nil {
+			return nil, fmt.Errorf("file descriptor %d for -log-file is not open: %s", fd, err)
+		}
+		return f, nil
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %s", err)
+	}
+	return f, nil
+}
+
+func fatal(args ...interface{}) {
+	logger.Error(fmt.Sprint(args...))
+	os.Exit(exitFailure)
+}
+
+func fatalf(format string, args ...interface{}) {
+	logger.Error(fmt.Sprintf(format, args...))
+	os.Exit(exitFailure)
+}
+
+// usageError fails on a wrong command line
+func usageError(format string, args ...interface{}) {
+	logger.Error(fmt.Sprintf(format, args...))
+	os.Exit(exitUsage)
+}
+
+// summarizeUnresolved logs the packages that failed to resolve in best
+// effort mode once more at the end of the run, so they are not lost among
+// other output. They are grouped by cause, so that one missing module is
+// one line however many of its packages are imported.
+func summarizeUnresolved() {
+	causes, members := failureCauses()
+	for _, cause := range causes {
+		names := members[cause]
+		logger.Error(fmt.Sprintf("%s caused %d package %s", cause, len(names), choose(len(names) == 1, "failure", "failures")), "pkgs", names)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitStatus(t *testing.T) {
+	cycs := [][]string{{"example.com/a", "example.com/b"}}
+	tests := []struct {
+		name       string
+		command    string
+		cycles     [][]string
+		violations bool
+		unresolved bool
+		want       int
+	}{
+		{"clean graph", "", nil, false, false, 0},
+		{"cycles in the graph", "", cycs, false, false, exitCycles},
+		{"cycles in stats", "stats", cycs, false, false, exitCycles},
+		{"cycles within the budget of check", "check", cycs, false, false, 0},
+		{"cycles allowed by conform", "conform", cycs, false, false, 0},
+		{"failed check", "check", cycs, true, false, exitViolations},
+		{"unresolved packages win", "", cycs, true, true, exitUnresolved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unresolved = make(map[string]error)
+			if tt.unresolved {
+				unresolved["example.com/missing"] = nil
+			}
+			defer func() { unresolved = make(map[string]error) }()
+			cyc, err := cyclic(tt.command, tt.cycles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := exitStatus(tt.violations, cyc); got != tt.want {
+				t.Errorf("exit status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodesDistinct(t *testing.T) {
+	seen := make(map[int]bool)
+	for _, code := range []int{0, exitFailure, exitCycles, exitViolations, exitUnresolved, exitUsage} {
+		if seen[code] {
+			t.Errorf("exit code %d is used twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestExemptedCycleExitsClean(t *testing.T) {
+	cycs := [][]string{{"example.com/a", "example.com/b"}}
+	tests := []struct {
+		name       string
+		exemptions string
+		want       int
+	}{
+		{"active exemption", "example.com/b = team 2999-12-31\n", 0},
+		{"expired exemption", "example.com/b = team 2000-01-01\n", exitCycles},
+		{"exemption of another package", "example.com/c = team 2999-12-31\n", exitCycles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "exemptions.txt")
+			if err := os.WriteFile(name, []byte(tt.exemptions), 0644); err != nil {
+				t.Fatal(err)
+			}
+			defer func(old string) { *exemptionsFile = old }(*exemptionsFile)
+			*exemptionsFile = name
+			cyc, err := cyclic("", cycs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := exitStatus(false, cyc); got != tt.want {
+				t.Errorf("exit status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,183 +1,631 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"go/build"
-	"log"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 )
 
 var (
-	pkgs            map[string]*build.Package
-	networkPackages map[string]string
+	pkgs map[string]*build.Package
 
 	ignored = map[string]bool{
 		"C": true,
 	}
 	ignoredPrefixes  []string
+	ignoredPatterns  []string
 	includedPackages []string
 	basePath         string
+	rootPackages     []string
+
+	// stdlibVendored maps imports of stdlib packages to the vendored
+	// packages they resolved to
+	stdlibVendored = make(map[string]string)
+
+	// unresolved holds the packages that failed to import in best effort
+	// mode, and why
+	unresolved = make(map[string]error)
+
+	commands = map[string]bool{
+		"check":     true,
+		"merge":     true,
+		"snapshot":  true,
+		"report":    true,
+		"diff":      true,
+		"blank":     true,
+		"docs":      true,
+		"aliases":   true,
+		"query":     true,
+		"stats":     true,
+		"migrate":   true,
+		"aggregate": true,
+		"modules":   true,
+		"why":       true,
+		"tests":     true,
+		"whatif":    true,
+		"split":     true,
+		"audit":     true,
+		"trend":     true,
+		"repl":      true,
+		"conform":   true,
+		"timelapse": true,
+		"freeze":    true,
+		"daemon":    true,
+	}
 
+	fromFile         = flag.String("from", "", "work on a graph saved with -format json instead of scanning packages. overlays that need the sources do not apply")
+	viewName         = flag.String("view", "", "apply the settings of this view, a [section] of the config file, on top of the others in there")
+	configFile       = flag.String("config", "", "read default flag values from this file. defaults to "+defaultConfigFile+" if it exists")
 	ignoreStdlib     = flag.Bool("s", false, "ignore packages in the go standard library")
 	ignorePrefixes   = flag.String("p", "", "a comma-separated list of prefixes to ignore")
 	ignorePackages   = flag.String("i", "", "a comma-separated list of packages to ignore")
+	scopedFile       = flag.String("scoped", "", "apply filters only to the imports of packages beneath a prefix, by \"prefix = action, ...\" lines in this file, where the actions are no-tests, no-imports and ignore <pattern>")
 	includePackages  = flag.String("n", "", "a comma-separated list of packages to always include, even if ignored before")
+	basePathFlag     = flag.String("basepath", "", "the base path of the graph, used by -b and -subgraph. defaults to the module of the first package, or its parent directory outside of modules")
+	strict           = flag.Bool("strict", false, "fail on the first package that cannot be resolved, instead of leaving it out")
+	gorootFlag       = flag.String("goroot", "", "the root of the standard library, for projects built with a custom or vendored toolchain. packages beneath its src directory count as standard library")
+	stdlibEdges      = flag.Bool("stdlib-edges", false, "also follow and render the imports between standard library packages")
+	allMains         = flag.Bool("all-mains", false, "take every main package of the module around the given directory, or the current one, as a root, and color the roots apart")
+	withTests        = flag.Bool("t", false, "also follow the test imports of packages in the base path, and draw their external test packages as separate nodes")
+	constraintsFlag  = flag.String("constraints", "", "a comma-separated list of constraint sets like linux, windows or linux+arm64+purego to also resolve packages under, labelling the imports only some of them make")
+	platformsFlag    = flag.String("platforms", "", "a comma-separated list of GOOS/GOARCH pairs to also scan under, marking the packages and imports only some of them have")
 	filterByBasePath = flag.Bool("b", false, "filer only for packages that are in the base path. other packages will be ignored except i if in includePackages")
+	ghostNodes       = flag.Bool("ghosts", false, "draw filtered packages that visible packages import as small grey nodes instead of dropping the edges")
+	granularity      = flag.String("granularity", "package", "draw nodes per package, or per file to break the packages in the base path up into their Go files")
+	metadataFile     = flag.String("metadata", "", "add the descriptions and documentation urls of packages from a file with a [package] section each to tooltips, node links and the docs site")
+	cutEdgesFlag     = flag.String("cut-edges", "", "a comma-separated list of from->to rules, by import path or glob pattern, whose edges are left out of the graph while their packages stay")
+	setView          = flag.String("set", "", "given several root packages, only show the dependencies shared by all of them (intersection), any of them (union) or only one of them (unique:<pkg>)")
+	reachableToFlag  = flag.String("reachable-to", "", "a comma-separated list of packages, to only show the packages that import any of them directly or indirectly")
+	focusFile        = flag.String("focus-file", "", "only show the packages listed one per line in this file and the edges among them")
+	focusNeighbours  = flag.Bool("focus-neighbours", false, "focus-file: also show the packages the listed ones import or are imported by directly")
+	parallelism      = flag.Int("j", runtime.NumCPU(), "how many packages to load in parallel, 1 to load them one after the other")
+	sampleSize       = flag.Int("sample", 0, "only show this many packages: the roots, the most imported ones and a sample of the rest, for a first look at huge graphs")
+	renameFile       = flag.String("rename", "", "only show the imports that renaming import paths by the \"old = new\" lines in this file rewrites. migrate: list them by file")
+	contractChains   = flag.Bool("contract-chains", false, "replace chains of packages with exactly one importer and one import by a single edge")
+	groupsFile       = flag.String("groups", "", "draw packages matching the prefixes in this file, given as lines like \"prefix = group\", in one box per group, and count the edges between groups")
+	classifyFile     = flag.String("classify", "", "assign packages categories by \"pattern = category\" lines in this file, drawn in the color, shape and box its [category] sections give")
+	classifyExec     = flag.String("classify-exec", "", "run this command with the nodes as JSON on stdin to assign categories, read back as \"import path = category\" lines. overrides -classify rules")
+	collapseGroups   = flag.Bool("collapse-groups", false, "draw every group, from -groups or //godepgraph:group directives, as a single node, with one edge per pair of groups labelled with the number of imports between them")
+	namespaceFlag    = flag.String("namespace", "", "prefix the DOT node names with this namespace instead of the base path, or none, to concatenate DOT from several runs safely")
 	subgraph         = flag.Bool("subgraph", false, "put graph into a subgraph box")
 	networkSubgraphs = flag.Bool("network-subgraphs", false, "for each always included package, put an own external subgraph. requires subgraph to be set")
+	markAliases      = flag.Bool("aliases", false, "highlight dot imports and imports of packages that go by different names across the graph")
+	fanIn            = flag.Bool("fan-in", false, "draw the border of every package as thick as the number of packages importing it")
+	concentrate      = flag.Bool("concentrate", false, "merge parallel edges and let dot bundle edges sharing a target, for dense graphs")
+	provenance       = flag.Bool("provenance", false, "list the files and lines of the import declarations making every edge in the JSON output")
+	outputFormat     = flag.String("format", "dot", "output format: dot, json, jsonl to stream the nodes and edges one per line, dsm or depguard")
+	anonymize        = flag.Bool("anonymize", false, "replace all import paths outside the standard library by hashes under a random key, to share the graph without giving away names")
+	anonymizeKey     = flag.String("anonymize-key", "", "anonymize: the secret key of the hashes, to get the same ones across runs. keep it to yourself")
+	renderFormat     = flag.String("render", "", "render the graph with graphviz dot to this format, e.g. svg or png. svg also works without graphviz")
+	validate         = flag.Bool("validate", false, "check that the generated DOT is well-formed before printing or rendering it, and fail if not")
+	showComplexity   = flag.Bool("complexity", false, "compute cyclomatic complexity per package and scale nodes accordingly")
+	gocycloFile      = flag.String("gocyclo", "", "read per-function complexity from gocyclo output in this file instead of computing it. implies complexity")
+	showSimplified   = flag.Bool("simplified", false, "log what the filters and views left out of the graph at info level, shown with -v, and note it below the drawing")
+	showSize         = flag.Bool("size", false, "show the number of Go files and lines of code of each package in the label; JSON always has them")
+	showExported     = flag.Bool("exported", false, "count the exported identifiers of each package and show them in the label and JSON")
+	symbolEdges      = flag.Bool("symbols", false, "type check the packages and label every edge with the number of exported identifiers the importer uses from the import")
+	expandPackages   = flag.String("expand", "", "a comma-separated list of packages whose incoming edges are replaced by edges to the identifiers the importers use")
+	binaryFile       = flag.String("binary", "", "attribute the symbol sizes of this built binary to packages and scale nodes accordingly")
+	churnDays        = flag.Int("churn", 0, "color packages by the number of commits that changed them in the last this many days, from git log")
+	showAge          = flag.Bool("age", false, "color packages by how long ago their files last changed, from git log or the file times")
+	showAuthors      = flag.Bool("authors", false, "color packages by the author of most of their lines, from git blame")
+	teamsFile        = flag.String("teams", "", "authors: map author emails to teams with lines like \"email = team\" in this file. implies authors")
+	showOutdated     = flag.Bool("outdated", false, "color packages of modules the go.mod requires in a version behind the latest on the module proxy")
+	showDeprecated   = flag.Bool("deprecated", false, "outline packages of modules deprecated in their latest go.mod on the module proxy, and the own packages importing them")
+	maxExternal      = flag.Int("max-external-modules", -1, "check: maximum number of external modules the root may depend on")
+	maxDepth         = flag.Int("max-depth", -1, "check: maximum length of the longest import chain from the root")
+	maxCycles        = flag.Int("max-cycles", -1, "check: maximum number of import cycles")
+	allowedFile      = flag.String("allowed", "", "check: fail on external modules and imports between own packages missing from this manifest, as written by freeze")
+	exemptionsFile   = flag.String("exemptions", "", "check: exempt external modules and cycles through a package from the budgets by \"subject = owner yyyy-mm-dd\" lines in this file, until the date passes")
+	snapshotDir      = flag.String("snapshot-dir", "godepgraph-snapshots", "snapshot, report: directory holding the dated graph snapshots")
+	testBinary       = flag.String("test-binary", "", "tests: only draw the test binary of this package instead of all of them")
+	modelFile        = flag.String("model", "", "conform: DOT file of the intended architecture, with components as nodes and the imports allowed between them as edges")
+	modelDiff        = flag.String("model-diff", "", "conform: also draw the allowed, missing and illegal imports between the components to this file, in the format of -render")
+	listenAddr       = flag.String("listen", "127.0.0.1:7878", "daemon: address to answer queries on, only for that host name or localhost")
+	pollInterval     = flag.Duration("poll", 2*time.Second, "daemon: how often to check the work tree for changed files")
+	daemonAddr       = flag.String("daemon", "", "query the daemon listening at this address instead of scanning, for the graph or a subcommand the repl runs")
+	replOut          = flag.String("repl-out", "godepgraph.dot", "repl: file to write the graph to after every change")
+	frameInterval    = flag.Int("interval", 1000, "timelapse: milliseconds each graph is shown for when playing")
+	trendTop         = flag.Int("top", 10, "trend: how many packages to list, -1 for all")
+	docsDir          = flag.String("o", "site", "docs: directory to write the documentation site to")
+	reposFile        = flag.String("repos", "", "aggregate: file listing the repositories to scan as \"name = path\" lines")
+	splitCandidates  = flag.Int("candidates", 10, "split: how many directory trees to list as candidate module boundaries, those with the fewest crossing imports first, -1 for all")
+	removePackages   = flag.String("remove", "", "whatif: a comma-separated list of packages to simulate removing")
+	importsAll       = flag.String("imports-all", "", "query: a comma-separated list of packages, to print the packages importing all of them")
+	diffSummary      = flag.String("summary", "", "diff, report: print a concise summary as text or markdown instead of the full list of changes")
+	explainIgnored   = flag.Bool("explain-ignored", false, "instead of the graph, print every package that was left out and the filter that removed it")
+	cyclesJSON       = flag.String("cycles-json", "", "also write the import cycles as a JSON array of ordered package lists with the closing edge to this file, or - for stdout")
+	dryRun           = flag.Bool("dry-run", false, "resolve the root packages, check the settings for conflicts and describe the run, without scanning or printing the graph")
+	verbose          = flag.Bool("v", false, "log progress and skipped packages")
+	veryVerbose      = flag.Bool("vv", false, "log every package loaded and other debugging details")
+	quiet            = flag.Bool("q", false, "only log errors")
+	logFile          = flag.String("log-file", "", "log to this file, appending, or to this open file descriptor if it is a number, instead of stderr")
+	logJSON          = flag.Bool("log-json", false, "log to stderr as JSON lines")
+	actionGraphFile  = flag.String("actiongraph", "", "annotate nodes with compile times from the output of go build -debug-actiongraph and highlight the critical path")
 )
 
 func main() {
 	pkgs = make(map[string]*build.Package)
-	networkPackages = make(map[string]string)
-	flag.Parse()
 
-	args := flag.Args()
+	// subcommands take the same flags as the graph itself
+	command := ""
+	args := os.Args[1:]
+	if len(args) > 0 && commands[args[0]] {
+		command, args = args[0], args[1:]
+	}
+	// the flag package prints what is wrong, the exit code sets bad flags
+	// apart from the outcomes of the analysis
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(args); err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(exitUsage)
+	}
 
-	if len(args) != 1 {
-		log.Fatal("need one package name to process")
+	if err := configure(); err != nil {
+		fatal(err)
 	}
+	setupLogging()
+
+	args = flag.Args()
 
-	if *ignorePrefixes != "" {
-		ignoredPrefixes = sanitizeCSV(*ignorePrefixes)
+	if command == "merge" {
+		if err := runMerge(args); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if command == "report" {
+		if err := runReport(*snapshotDir); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if command == "trend" {
+		if err := runTrend(args, *snapshotDir); err != nil {
+			fatal(err)
+		}
+		return
 	}
-	if *ignorePackages != "" {
-		for _, p := range sanitizeCSV(*ignorePackages) {
-			ignored[p] = true
+	if command == "timelapse" {
+		if err := runTimelapse(args, *snapshotDir); err != nil {
+			fatal(err)
 		}
+		return
 	}
-	if *includePackages != "" {
-		includedPackages = sanitizeCSV(*includePackages)
+	if command == "diff" {
+		if err := runDiff(args); err != nil {
+			fatal(err)
+		}
+		return
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("failed to get cwd: %s", err)
+	if *daemonAddr != "" {
+		os.Exit(queryDaemon(*daemonAddr, command, args))
+	}
+	if command == "daemon" {
+		if *fromFile != "" || len(args) != 1 || args[0] == "-" {
+			usageError("daemon needs one package name to scan")
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			fatal(err)
+		}
+		if err := runDaemon(args[0], cwd); err != nil {
+			fatal(err)
+		}
+		return
 	}
-	if err := processPackage(cwd, args[0]); err != nil {
-		log.Fatal(err)
+	if *fromFile != "" {
+		os.Exit(runLoaded(command, *fromFile))
 	}
 
-	fmt.Println("digraph godep {")
+	if *allMains && len(args) == 0 {
+		args = []string{"."}
+	}
+	if len(args) != 1 && command != "aggregate" {
+		usageError("need one package name to process, or - to read them from stdin")
+	}
 
-	if *subgraph && basePath != "" {
-		printSubgraphHead(basePath)
+	if err := setupFilters(); err != nil {
+		fatal(err)
+	}
+	basePath = strings.TrimSuffix(filepath.ToSlash(*basePathFlag), "/")
+	if *gorootFlag != "" {
+		if err := useGoroot(*gorootFlag); err != nil {
+			fatal(err)
+		}
+	}
+	if *constraintsFlag != "" && *platformsFlag != "" {
+		fatal("-constraints and -platforms do not go together")
+	}
+	if *constraintsFlag != "" {
+		sets, err := parseConstraints(*constraintsFlag)
+		if err != nil {
+			fatal(err)
+		}
+		constraintSets = sets
+	}
+	if *platformsFlag != "" {
+		sets, err := parsePlatforms(*platformsFlag)
+		if err != nil {
+			fatal(err)
+		}
+		usePlatforms(sets)
 	}
 
-	for pkgName, pkg := range pkgs {
-		pkgId := pkgName
+	cwd, err := os.Getwd()
+	if err != nil {
+		fatalf("failed to get cwd: %s", err)
+	}
+	cwd = canonicalDir(cwd)
 
-		if isIgnored(pkg) {
-			continue
-		}
+	if command == "aggregate" {
+		os.Exit(runAggregate(*reposFile))
+	}
 
-		var color string
-		if pkg.Goroot {
-			color = "palegreen"
-		} else if len(pkg.CgoFiles) > 0 {
-			color = "darkgoldenrod1"
-		} else if hasPrefixes(pkg.ImportPath, includedPackages) {
-			color = "violet"
-		} else {
-			color = "paleturquoise"
+	rootArgs := args
+	if *allMains {
+		if args[0] == "-" {
+			fatal("-all-mains finds the roots itself and does not read them from stdin")
 		}
+		mains, importPath, err := findMains(cwd, args[0])
+		if err != nil {
+			fatal(err)
+		}
+		if basePath == "" {
+			basePath = importPath
+		}
+		rootArgs = mains
+	} else if args[0] == "-" {
+		if rootArgs, err = readLines(os.Stdin); err != nil {
+			fatalf("failed to read packages from stdin: %s", err)
+		}
+		if len(rootArgs) == 0 {
+			fatal("need at least one package name on stdin")
+		}
+	}
+	for i, arg := range rootArgs {
+		rootArgs[i] = importArg(arg)
+	}
 
-		printNode(pkgName, color)
+	if command == "tests" {
+		// test binaries link in the test imports
+		*withTests = true
+	}
 
-		// Don't render imports from packages in Goroot
-		if pkg.Goroot {
-			//continue
+	if *dryRun {
+		if !runDryRun(cwd, command, rootArgs) {
+			os.Exit(exitFailure)
 		}
+		return
+	}
 
-		for _, imp := range pkg.Imports {
-			impPkg := pkgs[imp]
-			if impPkg == nil || isIgnored(impPkg) {
-				continue
-			}
+	if *outputFormat == "jsonl" && command == "" && !*explainIgnored {
+		if conflicts := streamConflicts(); len(conflicts) > 0 {
+			usageError("-format jsonl streams the packages as it finds them and does not work with %s, which %s the whole graph", strings.Join(conflicts, ", "), choose(len(conflicts) == 1, "needs", "need"))
+		}
+		// streams as it walks, without loading the packages first
+		if err := writeStream(os.Stdout, rootArgs, cwd); err != nil {
+			fatal(err)
+		}
+		if len(unresolved) > 0 {
+			summarizeUnresolved()
+		}
+		// the walk keeps no graph to find cycles in
+		os.Exit(exitStatus(false, false))
+	}
 
-			impId := imp
-			printEdge(pkgId, impId)
+	prefetch(rootArgs, cwd)
+	for _, arg := range rootArgs {
+		if root, err := processPackage(cwd, arg); err != nil {
+			fatal(err)
+		} else if root != nil {
+			rootPackages = append(rootPackages, root.ImportPath)
 		}
+	}
+	if len(rootPackages) == 0 && len(unresolved) > 0 {
+		fatal("none of the packages could be resolved")
+	}
+	if *explainIgnored {
+		printOmitted()
+		return
+	}
 
-		// check if we need to build a network subgraph for this node later
-		if *networkSubgraphs &&
-			hasPrefixes(pkg.ImportPath, includedPackages) &&
-			!strings.HasPrefix(pkg.ImportPath, basePath) {
-			networkPackages[pkgName] = pkgId
+	violations := false
+	if command == "blank" {
+		err = printBlankImports()
+	} else if command == "aliases" {
+		err = printAliases()
+	} else if command == "migrate" {
+		var r renames
+		if *renameFile == "" {
+			err = fmt.Errorf("migrate needs -rename")
+		} else if r, err = loadRenames(*renameFile); err == nil {
+			err = printMigration(r)
+		}
+	} else if *outputFormat == "depguard" {
+		err = printDepguard(os.Stdout)
+	} else if command == "repl" {
+		var g *graph
+		if g, err = withoutViews(buildGraph); err != nil {
+			fatal(err)
+		}
+		if err := runREPL(g); err != nil {
+			fatal(err)
+		}
+		return
+	} else {
+		var g *graph
+		if g, err = buildGraph(); err != nil {
+			fatal(err)
 		}
+		if command == "tests" {
+			tested := testedPackages()
+			if *testBinary != "" {
+				if !stringSet(tested)[*testBinary] {
+					fatalf("%s has no tests in the base path", *testBinary)
+				}
+				tested = []string{*testBinary}
+			}
+			g.testBinaries(tested)
+		}
+		violations, err = runGraphCommand(command, g)
+	}
+	if err != nil {
+		fatal(err)
 	}
 
-	if *subgraph && basePath != "" {
-		fmt.Println("}")
+	if len(unresolved) > 0 {
+		summarizeUnresolved()
+	}
+	cyc, err := cyclic(command, cycles())
+	if err != nil {
+		fatal(err)
+	}
+	os.Exit(exitStatus(violations, cyc))
+}
+
+// setBasePath sets the base path from the package if it has been neither
+// given nor set yet
+func setBasePath(pkg *build.Package) {
+	if basePath != "" {
+		return
+	}
+	// we assume that the first package we encouter is the root node
+	// the base path is the module of the root node, or if it is not in
+	// a module, the root node's parent directory
+	if mod := goModPath(pkg.Dir); mod != "" && strings.HasPrefix(pkg.ImportPath, mod) {
+		basePath = mod
+	} else {
+		basePathSplit := strings.Split(pkg.ImportPath, "/")
+		basePath = strings.Join(basePathSplit[0:len(basePathSplit)-1], "/")
 	}
+}
 
-	for pkgName, pkgId := range networkPackages {
-		// make subgraph
-		nameSplit := strings.Split(pkgName, "/")
-		name := nameSplit[len(nameSplit)-1]
-		printSubgraphHead(name)
-		printNode(name, "paleturquoise")
-		fmt.Println("}")
+// runGraphCommand runs a subcommand that works on the graph alone, or prints
+// the graph. It reports whether a check failed.
+func runGraphCommand(command string, g *graph) (bool, error) {
+	if *cyclesJSON != "" {
+		if err := writeCycles(*cyclesJSON, g); err != nil {
+			return false, err
+		}
+	}
+	switch command {
+	case "check":
+		ok, err := runCheck(g)
+		return !ok, err
+	case "conform":
+		return runConform(g)
+	case "freeze":
+		return false, printFreeze(g)
+	case "snapshot":
+		g.addMetrics()
+		return false, writeSnapshot(*snapshotDir, g)
+	case "docs":
+		return false, writeDocs(*docsDir, g)
+	case "query":
+		return false, runQuery(g)
+	case "stats":
+		return false, printStats(g)
+	case "modules":
+		return false, printAttributions(g)
+	case "why":
+		return false, printIntroductions(g)
+	case "whatif":
+		return false, runWhatIf(g)
+	case "split":
+		return false, printBoundaries(g)
+	case "audit":
+		return false, runAudit(g)
+	}
+	return false, writeGraph(os.Stdout, g)
+}
 
-		// make edge
-		printEdge(pkgId, name)
+// runLoaded runs a subcommand on a graph saved with -format json instead of
+// scanning the packages, and returns the exit code
+func runLoaded(command, name string) int {
+	if command == "blank" || command == "aliases" || command == "migrate" || command == "tests" || command == "audit" || *outputFormat == "depguard" || *outputFormat == "jsonl" {
+		fatalf("%s needs to scan the packages and does not work with -from", choose(command != "", command, "-format "+*outputFormat))
+	}
+	g, err := readGraph(name)
+	if err != nil {
+		fatal(err)
+	}
+	if *namespaceFlag != "" {
+		g.Namespace = graphNamespace()
+	}
+	for _, n := range g.Nodes {
+		if n.Error != "" {
+			unresolved[n.ID] = errors.New(n.Error)
+		}
+	}
+	if command == "repl" {
+		if err := runREPL(g); err != nil {
+			fatal(err)
+		}
+		return 0
+	}
+	if err := g.applyViews(); err != nil {
+		fatal(err)
+	}
+	violations, err := runGraphCommand(command, g)
+	if err != nil {
+		fatal(err)
 	}
+	if len(unresolved) > 0 {
+		summarizeUnresolved()
+	}
+	cyc, err := cyclic(command, g.cycles())
+	if err != nil {
+		fatal(err)
+	}
+	return exitStatus(violations, cyc)
+}
 
-	fmt.Println("}")
+// writeGraph writes the graph to w in the format selected by -format and
+// -render
+func writeGraph(w io.Writer, g *graph) error {
+	if *showSimplified {
+		// logged before anonymizing, as the log stays on this machine
+		reportSimplifications(g)
+	}
+	if *anonymize {
+		g.anonymize()
+	}
+	switch *outputFormat {
+	case "dot":
+		return renderDot(w, g, *renderFormat)
+	case "json":
+		return writeJSON(w, g)
+	case "dsm":
+		return writeDSM(w, g)
+	}
+	return fmt.Errorf("unknown output format %q", *outputFormat)
 }
 
-func processPackage(root string, pkgName string) error {
+// processPackage loads a package and, recursively, its imports. It returns
+// the loaded package, or nil if the package is ignored.
+func processPackage(root string, pkgName string) (*build.Package, error) {
 	if ignored[pkgName] {
-		return nil
+		skip(pkgName, "ignored by -i")
+		return nil, nil
+	}
+	// filters that only look at the import path can be applied before the
+	// package is imported, which may fail. Relative paths are only known
+	// after importing.
+	if reason := ignoreReason(&build.Package{ImportPath: pkgName}); reason != "" && !build.IsLocalImport(pkgName) {
+		skip(pkgName, reason)
+		return nil, nil
 	}
 
-	pkg, err := build.Import(pkgName, root, 0)
+	logger.Debug("loading package", "pkg", pkgName)
+	pkg, err := cachedImport(pkgName, root)
 	if err != nil {
-		return fmt.Errorf("failed to import %s: %s", pkgName, err)
+		err = fmt.Errorf("failed to import %s: %s", pkgName, err)
+		if *strict {
+			return nil, err
+		}
+		// best effort: note the failure and carry on without the package.
+		// The failures are summed up by cause at the end of the run.
+		logger.Info("skipped unresolvable package", "pkg", pkgName, "err", err)
+		unresolved[pkgName] = err
+		return nil, nil
 	}
 
-	if isIgnored(pkg) {
-		return nil
+	if build.IsLocalImport(pkg.ImportPath) {
+		// outside of GOPATH, relative paths only resolve through go.mod
+		pkg.ImportPath = moduleImportPath(pkg.Dir, pkg.ImportPath)
 	}
 
-	if basePath == "" {
-		// basePath has not been set yet
-		// we assume that the first package we encouter is the root node
-		// we assume that the base path is the root node's parent directory
-		basePathSplit := strings.Split(pkg.ImportPath, "/")
-		basePath = strings.Join(basePathSplit[0:len(basePathSplit)-1], "/")
+	if reason := ignoreReason(pkg); reason != "" {
+		skip(pkg.ImportPath, reason)
+		return nil, nil
 	}
 
+	setBasePath(pkg)
 	pkgs[pkg.ImportPath] = pkg
+	loadConditional(pkg)
 
-	// Don't worry about dependencies for stdlib packages
-	if pkg.Goroot {
-		return nil
+	// Don't worry about dependencies for stdlib packages, unless asked to
+	if pkg.Goroot && !*stdlibEdges {
+		return pkg, nil
 	}
 
-	for _, imp := range pkg.Imports {
-		if _, ok := pkgs[imp]; !ok {
-			if err := processPackage(root, imp); err != nil {
-				return err
+	follow := imports(pkg)
+	if testsEnabled(pkg) {
+		follow = append(append([]string(nil), follow...), scopedImports(pkg, pkg.XTestImports)...)
+	}
+	for _, imp := range follow {
+		if pkg.Goroot {
+			// the stdlib resolves some imports to its own vendor directory
+			if err := processStdlibImport(pkg, imp); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, ok := pkgs[imp]; !ok && unresolved[imp] == nil {
+			if _, err := processPackage(root, imp); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return nil
+	return pkg, nil
+}
+
+// processStdlibImport loads an import of a stdlib package relative to its
+// directory, and remembers where vendored imports resolved to.
+func processStdlibImport(pkg *build.Package, imp string) error {
+	if _, ok := pkgs[stdlibImport(imp)]; ok || unresolved[imp] != nil {
+		return nil
+	}
+	impPkg, err := processPackage(pkg.Dir, imp)
+	if err == nil && impPkg != nil && impPkg.ImportPath != imp {
+		stdlibVendored[imp] = impPkg.ImportPath
+	}
+	return err
+}
+
+// stdlibImport returns the import path an import of a stdlib package
+// resolved to
+func stdlibImport(imp string) string {
+	if vendored, ok := stdlibVendored[imp]; ok {
+		return vendored
+	}
+	return imp
+}
+
+// readLines returns the non-empty lines of r, trimmed
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, s.Err()
 }
 
 func sanitizeCSV(csv string) []string {
 	output := strings.Split(csv, ",")
 	for i, v := range output {
-		output[i] = strings.ToLower(strings.TrimSpace(v))
+		output[i] = filepath.ToSlash(strings.ToLower(strings.TrimSpace(v)))
 	}
 	return output
 }
 
 func hasPrefixes(s string, prefixes []string) bool {
 	for _, p := range prefixes {
-		if strings.HasPrefix(s, p) {
+		if matchesPrefix(s, p) {
 			return true
 		}
 	}
@@ -185,41 +633,30 @@ func hasPrefixes(s string, prefixes []string) bool {
 }
 
 func isIgnored(pkg *build.Package) bool {
-	return !hasPrefixes(pkg.ImportPath, includedPackages) &&
-		(ignored[pkg.ImportPath] ||
-			(pkg.Goroot && *ignoreStdlib) ||
-			hasPrefixes(pkg.ImportPath, ignoredPrefixes) ||
-			isNotOfBasepath(pkg.ImportPath, basePath))
+	return ignoreReason(pkg) != ""
 }
 
-func isNotOfBasepath(importPath, basePath string) bool {
-	return *filterByBasePath && !strings.HasPrefix(importPath, basePath)
-}
-
-func printSubgraphHead(name string) {
-	fmt.Printf("subgraph \"cluster%s\" {\n", name)
-	fmt.Println("style=filled;")
-	fmt.Println("color=lightgrey;")
-	fmt.Printf("label=\"%s\"\n", name)
-}
-
-func printNode(name, color string) {
-	fmt.Printf("\"%s\" [label=\"%s\" style=\"filled\" color=\"%s\"];\n", ns(name), name, color)
-}
-
-func printEdge(source, dest string) {
-	fmt.Printf("\"%s\" -> \"%s\";\n", ns(source), ns(dest))
-}
-
-// namespace all nodes with basePath to unique nodes when combining several graphs
-func ns(name string) string {
-	return fmt.Sprintf("%s:%s", basePath, name)
+// isStdlib reports whether the import path belongs to the standard library,
+// also for packages that were never loaded because they were ignored.
+func isStdlib(path string) bool {
+	if pkg, ok := pkgs[path]; ok {
+		return pkg.Goroot
+	}
+	first := strings.SplitN(path, "/", 2)[0]
+	return !strings.Contains(first, ".")
 }
 
-func debug(args ...interface{}) {
-	fmt.Fprintln(os.Stderr, args...)
+func isNotOfBasepath(importPath, basePath string) bool {
+	return *filterByBasePath && !strings.HasPrefix(importPath, basePath)
 }
 
-func debugf(s string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, s, args...)
+func nodeColor(pkg *build.Package) string {
+	if pkg.Goroot {
+		return "palegreen"
+	} else if len(pkg.CgoFiles) > 0 {
+		return "darkgoldenrod1"
+	} else if hasPrefixes(pkg.ImportPath, includedPackages) {
+		return "violet"
+	}
+	return "paleturquoise"
 }
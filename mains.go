@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// findMains returns every main package in the module around dir as local
+// paths relative to cwd, along with the import path of the module. Outside
+// of modules, the directory tree of dir is searched. Like the go command,
+// it leaves out testdata, vendor and directories starting with . or _, as
+// well as nested modules.
+func findMains(cwd, dir string) ([]string, string, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	top, importPath := dir, ""
+	if name := goModFile(dir); name != "" {
+		top = filepath.Dir(name)
+		importPath = goModPath(top)
+	} else if pkg, err := build.ImportDir(dir, build.FindOnly); err == nil && !build.IsLocalImport(pkg.ImportPath) {
+		importPath = pkg.ImportPath
+	}
+
+	var mains []string
+	err := filepath.WalkDir(top, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != top {
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		pkg, err := build.ImportDir(path, 0)
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); !ok {
+				logger.Warn("skipped directory while looking for main packages", "dir", path, "err", err)
+			}
+			return nil
+		}
+		if pkg.Name != "main" {
+			return nil
+		}
+		rel, err := filepath.Rel(cwd, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, ".") {
+			rel = "./" + rel
+		}
+		mains = append(mains, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look for main packages: %s", err)
+	}
+	if len(mains) == 0 {
+		return nil, "", fmt.Errorf("no main packages under %s", top)
+	}
+	sort.Strings(mains)
+	return mains, importPath, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func writeJSON(w io.Writer, g *graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+func readGraph(name string) (*graph, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open graph: %s", err)
+	}
+	defer f.Close()
+
+	g := new(graph)
+	if err := json.NewDecoder(f).Decode(g); err != nil {
+		return nil, fmt.Errorf("failed to decode graph %s: %s", name, err)
+	}
+	return g, nil
+}
+
+// runMerge unions the JSON graphs in the given files into one graph and
+// prints it. Every node keeps the namespace of the graph it came from, so
+// the same package seen by two runs stays two nodes. Graphs sharing a
+// namespace, or having none, are told apart by their file name.
+func runMerge(files []string) error {
+	if len(files) < 2 {
+		return fmt.Errorf("need at least two graphs to merge")
+	}
+
+	merged := &graph{}
+	seen := make(map[string]bool)
+	for _, name := range files {
+		g, err := readGraph(name)
+		if err != nil {
+			return err
+		}
+		namespace := g.Namespace
+		if namespace == "" || seen[namespace] {
+			namespace = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		}
+		if seen[namespace] {
+			return fmt.Errorf("duplicate namespace %q in %s", namespace, name)
+		}
+		seen[namespace] = true
+
+		for _, n := range g.Nodes {
+			n.Namespace = namespace
+			merged.Nodes = append(merged.Nodes, n)
+		}
+		for _, e := range g.Edges {
+			e.Namespace = namespace
+			merged.Edges = append(merged.Edges, e)
+		}
+	}
+	return writeGraph(os.Stdout, merged)
+}
@@ -0,0 +1,58 @@
+package main
+
+import "fmt"
+
+// packageMetadata is the curated context of a package from -metadata
+type packageMetadata struct {
+	description, url string
+}
+
+// loadMetadata reads a file with one section per package, named by its
+// import path, holding a description and a documentation url:
+//
+//	[github.com/foo/app/api]
+//	description = public HTTP API
+//	url = https://wiki.foo.com/api
+func loadMetadata(name string) (map[string]packageMetadata, error) {
+	sections, err := readSections(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(sections[""]) > 0 {
+		return nil, fmt.Errorf("%s: settings outside of a package section", name)
+	}
+	metadata := make(map[string]packageMetadata)
+	for id, section := range sections {
+		if id == "" {
+			continue
+		}
+		for key := range section {
+			if key != "description" && key != "url" {
+				return nil, fmt.Errorf("%s: unknown setting %q for %s, expected description or url", name, key, id)
+			}
+		}
+		metadata[id] = packageMetadata{description: section["description"], url: section["url"]}
+	}
+	return metadata, nil
+}
+
+// addMetadata sets the description and documentation url of the packages
+// the metadata knows, adding them to the tooltip and as a link of the node
+func (g *graph) addMetadata(metadata map[string]packageMetadata) {
+	for _, n := range g.Nodes {
+		m, ok := metadata[n.ID]
+		if !ok {
+			continue
+		}
+		n.Description, n.URL = m.description, m.url
+		if n.Attrs == nil {
+			n.Attrs = attrs{}
+		}
+		if m.description != "" {
+			n.Attrs.add(attrs{"tooltip": m.description})
+		}
+		if m.url != "" {
+			n.Attrs["URL"] = m.url
+		}
+	}
+}
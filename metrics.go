@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// nodeMetrics measures the coupling of a package, as kept in snapshots
+type nodeMetrics struct {
+	FanIn  int `json:"fanIn"`
+	FanOut int `json:"fanOut"`
+	// Transitive is the number of packages the package depends on,
+	// directly or indirectly
+	Transitive int `json:"transitive"`
+}
+
+// coupling returns the metrics of every node of the graph besides filtered
+// and unresolved packages. Metrics stored with the nodes, as in snapshots,
+// are taken as they are.
+func (g *graph) coupling() map[string]*nodeMetrics {
+	next := g.imports()
+	importers := make(map[string]map[string]bool)
+	for _, n := range g.Nodes {
+		for _, imp := range next(n.ID) {
+			if importers[imp] == nil {
+				importers[imp] = make(map[string]bool)
+			}
+			importers[imp][n.ID] = true
+		}
+	}
+	metrics := make(map[string]*nodeMetrics)
+	for _, n := range g.Nodes {
+		if n.Ghost || n.Error != "" {
+			continue
+		}
+		if n.Metrics != nil {
+			metrics[n.ID] = n.Metrics
+			continue
+		}
+		reached := make(map[string]bool)
+		var visit func(v string)
+		visit = func(v string) {
+			for _, w := range next(v) {
+				if !reached[w] {
+					reached[w] = true
+					visit(w)
+				}
+			}
+		}
+		visit(n.ID)
+		delete(reached, n.ID)
+		metrics[n.ID] = &nodeMetrics{
+			FanIn:      len(importers[n.ID]),
+			FanOut:     len(stringSet(next(n.ID))),
+			Transitive: len(reached),
+		}
+	}
+	return metrics
+}
+
+// addMetrics stores the coupling metrics with the nodes
+func (g *graph) addMetrics() {
+	metrics := g.coupling()
+	for _, n := range g.Nodes {
+		n.Metrics = metrics[n.ID]
+	}
+}
+
+// runTrend prints the packages whose coupling grew the most between two
+// graphs: the given files, or the two latest snapshots
+func runTrend(files []string, dir string) error {
+	if len(files) == 0 {
+		all, err := snapshots(dir)
+		if err != nil {
+			return err
+		}
+		if len(all) < 2 {
+			return fmt.Errorf("need at least two snapshots in %s, found %d", dir, len(all))
+		}
+		files = all[len(all)-2:]
+	}
+	if len(files) != 2 {
+		return fmt.Errorf("need an old and a new graph to compare")
+	}
+	before, err := readGraph(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := readGraph(files[1])
+	if err != nil {
+		return err
+	}
+
+	old, now := before.coupling(), after.coupling()
+	type change struct {
+		id                string
+		before, after     nodeMetrics
+		coupling, reached int
+	}
+	var changes []*change
+	for id, m := range now {
+		c := &change{id: id, after: *m}
+		if o, ok := old[id]; ok {
+			c.before = *o
+		}
+		c.coupling = c.after.FanIn + c.after.FanOut - c.before.FanIn - c.before.FanOut
+		c.reached = c.after.Transitive - c.before.Transitive
+		if c.coupling > 0 || c.reached > 0 {
+			changes = append(changes, c)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].coupling != changes[j].coupling {
+			return changes[i].coupling > changes[j].coupling
+		}
+		if changes[i].reached != changes[j].reached {
+			return changes[i].reached > changes[j].reached
+		}
+		return changes[i].id < changes[j].id
+	})
+	if *trendTop >= 0 && len(changes) > *trendTop {
+		changes = changes[:*trendTop]
+	}
+
+	fmt.Printf("Coupling changes from %s to %s\n\n", snapshotName(files[0]), snapshotName(files[1]))
+	if len(changes) == 0 {
+		fmt.Println("No package's coupling grew.")
+	}
+	metric := func(name string, before, after int) string {
+		return fmt.Sprintf("%s %d -> %d (%+d)", name, before, after, after-before)
+	}
+	for _, c := range changes {
+		fmt.Printf("%s: %s, %s, %s\n", c.id,
+			metric("fan-in", c.before.FanIn, c.after.FanIn),
+			metric("fan-out", c.before.FanOut, c.after.FanOut),
+			metric("transitive", c.before.Transitive, c.after.Transitive))
+	}
+	return nil
+}
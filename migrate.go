@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// renames maps old import path prefixes to new ones
+type renames map[string]string
+
+func loadRenames(name string) (renames, error) {
+	config, err := readConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return renames(config), nil
+}
+
+// rewrite returns the new import path of imp by the longest old prefix it
+// starts with, on path element boundaries, and whether there was one
+func (r renames) rewrite(imp string) (string, bool) {
+	best := ""
+	for old := range r {
+		if (imp == old || strings.HasPrefix(imp, old+"/")) && len(old) > len(best) {
+			best = old
+		}
+	}
+	if best == "" {
+		return imp, false
+	}
+	return r[best] + imp[len(best):], true
+}
+
+// migrationView reduces the graph to the imports that need rewriting, with
+// the packages making them and the packages they point at. The edges are
+// labelled with the new import path.
+func (g *graph) migrationView(r renames) {
+	keep := make(map[string]bool)
+	var edges []*edge
+	for _, e := range g.Edges {
+		newPath, ok := r.rewrite(e.To)
+		if !ok {
+			continue
+		}
+		keep[e.From], keep[e.To] = true, true
+		if e.Attrs == nil {
+			e.Attrs = attrs{}
+		}
+		e.Attrs.add(attrs{"color": "blue", "tooltip": "becomes " + newPath})
+		edges = append(edges, e)
+	}
+	var nodes []*node
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			nodes = append(nodes, n)
+		}
+	}
+	g.Nodes, g.Edges = nodes, edges
+}
+
+// rewriteSite is an import declaration to rewrite
+type rewriteSite struct {
+	file         string
+	line         int
+	imp, newPath string
+}
+
+// migrationFiles returns all the Go files of the package that a rewrite has
+// to touch: the build files, the tests and the files the build constraints
+// leave out
+func migrationFiles(pkg *build.Package) []string {
+	var files []string
+	for _, names := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.IgnoredGoFiles} {
+		files = append(files, names...)
+	}
+	return files
+}
+
+// printMigration lists every import declaration in the files of the visible
+// packages that the renames rewrite, with the file and line, and sums up the
+// work
+func printMigration(r renames) error {
+	files := make(map[string]bool)
+	packages := make(map[string]bool)
+	var sites []rewriteSite
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot {
+			continue
+		}
+		for imp, specs := range parseImportSpecs(pkg, migrationFiles(pkg)) {
+			newPath, ok := r.rewrite(imp)
+			if !ok {
+				continue
+			}
+			for _, s := range specs {
+				file := pkgName + "/" + filepath.Base(s.pos.Filename)
+				files[file] = true
+				packages[pkgName] = true
+				sites = append(sites, rewriteSite{file, s.pos.Line, imp, newPath})
+			}
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		a, b := sites[i], sites[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		return a.imp < b.imp
+	})
+	for _, s := range sites {
+		fmt.Printf("%s:%d: %s -> %s\n", s.file, s.line, s.imp, s.newPath)
+	}
+	fmt.Printf("%d imports in %d files of %d packages to rewrite\n", len(sites), len(files), len(packages))
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationCoversAllFilesInLineOrder(t *testing.T) {
+	resetFilters(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		// the imports on lines 9 and 10, to sort by number rather than text
+		"app.go":         "package app\n\nimport (\n\t\"fmt\"\n\n\n\n\n\t\"github.com/old/lib/a\"\n\t\"github.com/old/lib/b\"\n)\n",
+		"app_test.go":    "package app\n\nimport \"github.com/old/lib/testutil\"\n",
+		"ext_test.go":    "package app_test\n\nimport \"github.com/old/lib\"\n",
+		"app_windows.go": "package app\n\nimport \"github.com/old/lib/win\"\n",
+		"gen.go":         "//go:build ignore\n\npackage main\n\nimport \"github.com/old/lib/gen\"\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := build.Default
+	ctx.GOOS = "linux"
+	pkg, err := ctx.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg.ImportPath = "example.com/app"
+	saved := pkgs
+	pkgs = map[string]*build.Package{pkg.ImportPath: pkg}
+	t.Cleanup(func() { pkgs = saved })
+
+	var out bytes.Buffer
+	if _, err := captureStdout(&out, func() (bool, error) {
+		return false, printMigration(renames{"github.com/old/lib": "github.com/new/lib"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"example.com/app/app.go:9: github.com/old/lib/a -> github.com/new/lib/a",
+		"example.com/app/app.go:10: github.com/old/lib/b -> github.com/new/lib/b",
+		"example.com/app/app_test.go:3: github.com/old/lib/testutil -> github.com/new/lib/testutil",
+		"example.com/app/app_windows.go:3: github.com/old/lib/win -> github.com/new/lib/win",
+		"example.com/app/ext_test.go:3: github.com/old/lib -> github.com/new/lib",
+		"example.com/app/gen.go:5: github.com/old/lib/gen -> github.com/new/lib/gen",
+		"6 imports in 5 files of 1 packages to rewrite",
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
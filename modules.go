@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// modulePaths caches the module path found for a directory
+var modulePaths = make(map[string]string)
+
+// moduleOf returns the path of the module a package belongs to. It is read
+// from the nearest go.mod above the package directory; without one, the
+// repository root is guessed from the import path.
+func moduleOf(pkg *build.Package) string {
+	if pkg.Goroot {
+		return "std"
+	}
+	if pkg.Dir != "" {
+		if mod := goModPath(pkg.Dir); mod != "" && strings.HasPrefix(pkg.ImportPath, mod) {
+			return mod
+		}
+		if root := vcsRoot(pkg); root != "" {
+			return root
+		}
+	}
+	return repoRoot(pkg.ImportPath)
+}
+
+// vcsRoot returns the import path of the version control checkout holding
+// a package in GOPATH
+func vcsRoot(pkg *build.Package) string {
+	if pkg.SrcRoot == "" {
+		return ""
+	}
+	for dir := pkg.Dir; hasPathPrefix(dir, pkg.SrcRoot) && pathKey(dir) != pathKey(pkg.SrcRoot); dir = filepath.Dir(dir) {
+		for _, vcs := range []string{".git", ".hg", ".bzr", ".svn"} {
+			if _, err := os.Stat(filepath.Join(dir, vcs)); err == nil {
+				rel, err := filepath.Rel(pkg.SrcRoot, dir)
+				if err != nil {
+					return ""
+				}
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return ""
+}
+
+func goModPath(dir string) string {
+	if mod, ok := modulePaths[dir]; ok {
+		return mod
+	}
+	mod := ""
+	if f, err := os.Open(filepath.Join(dir, "go.mod")); err == nil {
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			fields := strings.Fields(s.Text())
+			if len(fields) == 2 && fields[0] == "module" {
+				mod = strings.Trim(fields[1], `"`)
+				break
+			}
+		}
+		f.Close()
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = goModPath(parent)
+	}
+	modulePaths[dir] = mod
+	return mod
+}
+
+// moduleImportPath returns the import path of the package in dir by the
+// go.mod above it, or fallback outside of modules
+func moduleImportPath(dir, fallback string) string {
+	name := goModFile(dir)
+	if name == "" {
+		return fallback
+	}
+	rel, err := filepath.Rel(filepath.Dir(name), dir)
+	if err != nil {
+		return fallback
+	}
+	return path.Join(goModPath(filepath.Dir(name)), filepath.ToSlash(rel))
+}
+
+// repoRoot guesses the repository of an import path: three elements for
+// hosts like github.com/user/repo, the whole path otherwise.
+func repoRoot(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	if len(parts) > 3 && strings.Contains(parts[0], ".") {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,78 @@
+package main
+
+import "fmt"
+
+// lag is how far the required version of a module is behind the latest
+type lag struct {
+	required, latest string
+	// level is major, minor or patch
+	level string
+}
+
+// lagColors fill the nodes of modules lagging behind by level
+var lagColors = map[string]string{
+	"major": "salmon",
+	"minor": "orange",
+	"patch": "khaki",
+}
+
+// loadOutdated compares the versions the go.mod of the first root requires
+// with the latest versions on the module proxy. A newer major version is
+// looked up under the next major version's module path.
+func loadOutdated() (map[string]*lag, error) {
+	if len(rootPackages) == 0 {
+		return nil, nil
+	}
+	name := goModFile(pkgs[rootPackages[0]].Dir)
+	if name == "" {
+		logger.Warn("no go.mod found, cannot check for outdated modules")
+		return nil, nil
+	}
+	required, err := requiredVersions(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", name, err)
+	}
+
+	lags := make(map[string]*lag)
+	for mod, version := range required {
+		current, ok := parseSemver(version)
+		if !ok {
+			continue
+		}
+		latest, err := latestVersion(mod)
+		if err != nil {
+			logger.Warn("failed to query module proxy", "module", mod, "err", err)
+			continue
+		}
+		l := &lag{required: version, latest: latest}
+		base, major := majorSuffix(mod)
+		if next, err := latestVersion(fmt.Sprintf("%s/v%d", base, major+1)); err == nil && next != "" {
+			l.latest, l.level = next, "major"
+		} else if newest, ok := parseSemver(latest); ok {
+			switch {
+			case newest.major > current.major:
+				l.level = "major"
+			case newest.major == current.major && newest.minor > current.minor:
+				l.level = "minor"
+			case newest.major == current.major && newest.minor == current.minor && newest.patch > current.patch:
+				l.level = "patch"
+			}
+		}
+		if l.level != "" {
+			lags[mod] = l
+		}
+	}
+	return lags, nil
+}
+
+func outdatedAttrs(mod string, lags map[string]*lag) attrs {
+	l, ok := lags[mod]
+	if !ok {
+		return nil
+	}
+	return attrs{
+		"label":     fmt.Sprintf("(%s -> %s)", l.required, l.latest),
+		"fillcolor": lagColors[l.level],
+		"tooltip":   fmt.Sprintf("%s update available: %s requires %s, latest is %s", l.level, mod, l.required, l.latest),
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+// overlay annotates the nodes or edges of the graph with data from outside
+// of the import graph, when the flag named like it is given
+type overlay struct {
+	name    string
+	enabled func() bool
+}
+
+// overlays are the overlays buildGraph adds, in the order it adds them. A
+// new overlay is registered here, so that -dry-run lists it too.
+var overlays = []overlay{
+	{"complexity", func() bool { return *showComplexity || *gocycloFile != "" }},
+	{"exported", func() bool { return *showExported }},
+	{"symbols", func() bool { return *symbolEdges }},
+	{"binary", func() bool { return *binaryFile != "" }},
+	{"actiongraph", func() bool { return *actionGraphFile != "" }},
+	{"churn", func() bool { return *churnDays > 0 }},
+	{"age", func() bool { return *showAge }},
+	{"authors", func() bool { return *showAuthors || *teamsFile != "" }},
+	{"aliases", func() bool { return *markAliases }},
+	{"outdated", func() bool { return *showOutdated }},
+	{"size", func() bool { return *showSize }},
+	{"provenance", func() bool { return *provenance }},
+	{"deprecated", func() bool { return *showDeprecated }},
+	{"ghosts", func() bool { return *ghostNodes }},
+}
+
+// overlayEnabled reports whether the command line selects the overlay
+func overlayEnabled(name string) bool {
+	for _, o := range overlays {
+		if o.name == name {
+			return o.enabled()
+		}
+	}
+	return false
+}
+
+// enabledOverlays returns the names of the overlays the command line selects
+func enabledOverlays() []string {
+	var names []string
+	for _, o := range overlays {
+		if o.enabled() {
+			names = append(names, o.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestOverlaysAreFlags(t *testing.T) {
+	for _, o := range overlays {
+		if flag.Lookup(o.name) == nil {
+			t.Errorf("overlay %s is named after no flag", o.name)
+		}
+	}
+}
+
+func TestEnabledOverlays(t *testing.T) {
+	teams, churn, ghosts := *teamsFile, *churnDays, *ghostNodes
+	t.Cleanup(func() { *teamsFile, *churnDays, *ghostNodes = teams, churn, ghosts })
+	*teamsFile, *churnDays, *ghostNodes = "teams.txt", 30, true
+	if got, want := strings.Join(enabledOverlays(), ","), "churn,authors,ghosts"; got != want {
+		t.Errorf("enabled overlays %s, want %s", got, want)
+	}
+	if !overlayEnabled("authors") || overlayEnabled("age") {
+		t.Errorf("-teams must enable authors and not age")
+	}
+}
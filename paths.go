@@ -0,0 +1,65 @@
+package main
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// pathKey normalizes a file system path for comparisons: cleaned, and case
+// folded on Windows, where paths are case insensitive.
+func pathKey(path string) string {
+	path = filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		path = strings.ToLower(path)
+	}
+	return path
+}
+
+// hasPathPrefix reports whether path is dir or lies below it
+func hasPathPrefix(path, dir string) bool {
+	path, dir = pathKey(path), pathKey(dir)
+	if !strings.HasPrefix(path, dir) {
+		return false
+	}
+	return len(path) == len(dir) || os.IsPathSeparator(path[len(dir)]) || os.IsPathSeparator(dir[len(dir)-1])
+}
+
+// realDir resolves the symbolic links in dir, for telling whether two
+// directories are the same. A directory that cannot be resolved is kept.
+func realDir(dir string) string {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		return real
+	}
+	return filepath.Clean(dir)
+}
+
+// canonicalDir rewrites dir to the spelling of the GOROOT or GOPATH entry
+// containing it. go/build compares directories case sensitively, so on
+// Windows a working directory of c:\gopath\src\... would otherwise not be
+// found in a GOPATH of C:\gopath.
+func canonicalDir(dir string) string {
+	dir = filepath.Clean(dir)
+	if runtime.GOOS != "windows" {
+		return dir
+	}
+	roots := append([]string{build.Default.GOROOT}, filepath.SplitList(build.Default.GOPATH)...)
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		root = filepath.Clean(root)
+		if hasPathPrefix(dir, root) {
+			return root + dir[len(root):]
+		}
+	}
+	return dir
+}
+
+// importArg turns a package argument into an import path. Local paths may
+// be written with the OS separator, like .\cmd\app on Windows.
+func importArg(arg string) string {
+	return filepath.ToSlash(arg)
+}
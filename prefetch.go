@@ -0,0 +1,124 @@
+package main
+
+import (
+	"go/build"
+	"sync"
+)
+
+// importKey identifies an import: the same path may resolve differently
+// from another directory, as in vendored imports of the standard library
+type importKey struct {
+	path, dir string
+}
+
+type importResult struct {
+	pkg *build.Package
+	err error
+}
+
+var (
+	importCacheMu sync.Mutex
+	// importCache holds the packages imported so far, shared by the
+	// parallel prefetch and the scan
+	importCache = make(map[importKey]*importResult)
+)
+
+// cachedImport imports a package like importPackage, once per path and
+// directory
+func cachedImport(pkgName, dir string) (*build.Package, error) {
+	key := importKey{pkgName, dir}
+	importCacheMu.Lock()
+	r, ok := importCache[key]
+	importCacheMu.Unlock()
+	if ok {
+		return r.pkg, r.err
+	}
+	pkg, err := importPackage(pkgName, dir)
+	importCacheMu.Lock()
+	importCache[key] = &importResult{pkg, err}
+	importCacheMu.Unlock()
+	return pkg, err
+}
+
+// prefetch imports the roots and everything they import with -j workers in
+// parallel, filling the import cache so that the scan that follows, which
+// applies the filters and builds the graph one package at a time, does not
+// wait for the file system or the go command. Only the filters that look at
+// the import path alone are applied here, the scan applies the rest. Test
+// imports are followed for the packages the scan follows them for, which
+// takes the base path, so it is set from the roots first.
+func prefetch(roots []string, dir string) {
+	if *parallelism < 2 {
+		return
+	}
+	for _, root := range roots {
+		if basePath != "" {
+			break
+		}
+		pkg, err := cachedImport(root, dir)
+		if err != nil {
+			continue
+		}
+		if build.IsLocalImport(pkg.ImportPath) {
+			pkg.ImportPath = moduleImportPath(pkg.Dir, pkg.ImportPath)
+		}
+		if ignoreReason(pkg) == "" {
+			setBasePath(pkg)
+		}
+	}
+	var (
+		mu   sync.Mutex
+		seen = make(map[importKey]bool)
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, *parallelism)
+	)
+	var visit func(pkgName, dir string)
+	visit = func(pkgName, dir string) {
+		if pkgName == "C" || ignored[pkgName] {
+			return
+		}
+		if !build.IsLocalImport(pkgName) && ignoreReason(&build.Package{ImportPath: pkgName}) != "" {
+			return
+		}
+		key := importKey{pkgName, dir}
+		mu.Lock()
+		done := seen[key]
+		seen[key] = true
+		mu.Unlock()
+		if done {
+			return
+		}
+		// a worker slot is taken before the goroutine starts, so that no
+		// more than -j of them are importing or waiting to
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pkg, err := cachedImport(pkgName, dir)
+			<-sem
+			if err != nil {
+				return
+			}
+			if pkg.Goroot {
+				if *stdlibEdges {
+					for _, imp := range pkg.Imports {
+						visit(imp, pkg.Dir)
+					}
+				}
+				return
+			}
+			follow := pkg.Imports
+			if testsEnabled(pkg) {
+				follow = append(append(append([]string(nil), follow...), pkg.TestImports...), pkg.XTestImports...)
+			}
+			for _, imp := range scopedImports(pkg, follow) {
+				visit(imp, dir)
+			}
+		}()
+	}
+	for _, root := range roots {
+		visit(root, dir)
+	}
+	wg.Wait()
+	logger.Debug("prefetched packages", "imports", len(seen), "workers", *parallelism)
+}
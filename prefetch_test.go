@@ -0,0 +1,50 @@
+package main
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrefetchFollowsTestsInBasePathOnly(t *testing.T) {
+	resetFilters(t)
+	gopath := t.TempDir()
+	files := map[string]string{
+		"example.com/app/app.go":      "package app\n\nimport _ \"example.com/dep\"\n",
+		"example.com/app/app_test.go": "package app\n\nimport _ \"example.com/apptest\"\n",
+		"example.com/apptest/t.go":    "package apptest\n",
+		"example.com/dep/dep.go":      "package dep\n",
+		"example.com/dep/dep_test.go": "package dep\n\nimport _ \"example.com/deptest\"\n",
+		"example.com/deptest/t.go":    "package deptest\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(gopath, "src", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("GO111MODULE", "off")
+	saved, cache, tests, workers := build.Default, importCache, *withTests, *parallelism
+	t.Cleanup(func() { build.Default, importCache, *withTests, *parallelism = saved, cache, tests, workers })
+	build.Default.GOPATH = gopath
+	importCache = make(map[importKey]*importResult)
+	*withTests, *parallelism, basePath = true, 4, "example.com/app"
+
+	prefetch([]string{"example.com/app"}, gopath)
+	loaded := make(map[string]bool)
+	for key := range importCache {
+		loaded[key.path] = true
+	}
+	for _, path := range []string{"example.com/app", "example.com/dep", "example.com/apptest"} {
+		if !loaded[path] {
+			t.Errorf("did not prefetch %s", path)
+		}
+	}
+	if loaded["example.com/deptest"] {
+		t.Errorf("prefetched a test import of a dependency outside the base path")
+	}
+}
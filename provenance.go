@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// source is an import declaration creating an edge, with -provenance
+type source struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// addProvenance records the import declarations behind every edge of a
+// scanned package. Edges only tests make point to the test files, those of
+// an external test package to its files, and imports only other
+// -constraints make to the files the default build context leaves out.
+func (g *graph) addProvenance() {
+	excluded := make(map[string]map[string][]importSpec)
+	tests := make(map[string]map[string][]importSpec)
+	for _, e := range g.Edges {
+		pkgName, external := strings.TrimSuffix(e.From, "_test"), strings.HasSuffix(e.From, "_test")
+		pkg := pkgs[pkgName]
+		if pkg == nil || !external && pkgs[e.From] == nil {
+			continue
+		}
+		var specs []importSpec
+		switch {
+		case external:
+			if tests[e.From] == nil {
+				tests[e.From] = parseImportSpecs(pkg, pkg.XTestGoFiles)
+			}
+			specs = tests[e.From][e.To]
+		case e.Test:
+			if tests[pkgName] == nil {
+				tests[pkgName] = parseImportSpecs(pkg, pkg.TestGoFiles)
+			}
+			specs = tests[pkgName][e.To]
+		default:
+			specs = importSpecs(pkg)[e.To]
+			if len(specs) == 0 && len(constraintSets) > 0 {
+				if excluded[pkgName] == nil {
+					excluded[pkgName] = parseImportSpecs(pkg, pkg.IgnoredGoFiles)
+				}
+				specs = excluded[pkgName][e.To]
+			}
+		}
+		for _, s := range specs {
+			e.Sources = append(e.Sources, source{File: filepath.ToSlash(s.pos.Filename), Line: s.pos.Line})
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var proxyClient = &http.Client{Timeout: 20 * time.Second}
+
+// moduleProxy returns the first module proxy of GOPROXY that can be queried
+func moduleProxy() string {
+	for _, p := range strings.FieldsFunc(os.Getenv("GOPROXY"), func(r rune) bool { return r == ',' || r == '|' }) {
+		if p != "direct" && p != "off" {
+			return strings.TrimSuffix(p, "/")
+		}
+	}
+	return "https://proxy.golang.org"
+}
+
+// escapeModulePath escapes upper case letters the way the module proxy
+// protocol wants them, as ! followed by the lower case letter
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// proxyGet fetches a file of a module from the module proxy. It returns
+// nil without error if the proxy does not know the module.
+func proxyGet(mod, file string) ([]byte, error) {
+	resp, err := proxyClient.Get(moduleProxy() + "/" + escapeModulePath(mod) + "/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s/%s: %s", mod, file, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// latestVersion asks the module proxy for the latest version of a module, ""
+// if there is none
+func latestVersion(mod string) (string, error) {
+	body, err := proxyGet(mod, "@latest")
+	if err != nil || body == nil {
+		return "", err
+	}
+	var info struct{ Version string }
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("%s/@latest: %s", mod, err)
+	}
+	return info.Version, nil
+}
+
+// goModFile returns the go.mod file closest above dir, "" if there is none
+func goModFile(dir string) string {
+	for {
+		name := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// requiredVersions reads the versions a go.mod requires, by module path
+func requiredVersions(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	required := make(map[string]string)
+	inBlock := false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case fields[0] == "require" && len(fields) == 3:
+			required[strings.Trim(fields[1], `"`)] = fields[2]
+		case inBlock && len(fields) == 2:
+			required[strings.Trim(fields[0], `"`)] = fields[1]
+		}
+	}
+	return required, s.Err()
+}
+
+// semver is a parsed module version, vMAJOR.MINOR.PATCH[-pre]
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+func parseSemver(v string) (semver, bool) {
+	var sv semver
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, sv.pre = v[:i], v[i+1:]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return sv, false
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return sv, false
+		}
+		nums[i] = n
+	}
+	sv.major, sv.minor, sv.patch = nums[0], nums[1], nums[2]
+	return sv, true
+}
+
+// majorSuffix splits the major version suffix off a module path, as in
+// example.com/mod/v2, returning the path without it and the major version
+func majorSuffix(mod string) (string, int) {
+	i := strings.LastIndex(mod, "/v")
+	if i < 0 {
+		return mod, 1
+	}
+	n, err := strconv.Atoi(mod[i+2:])
+	if err != nil || n < 2 {
+		return mod, 1
+	}
+	return mod[:i], n
+}
+
+// less reports whether v is an earlier version than w. A pre-release comes
+// before the release.
+func (v semver) less(w semver) bool {
+	switch {
+	case v.major != w.major:
+		return v.major < w.major
+	case v.minor != w.minor:
+		return v.minor < w.minor
+	case v.patch != w.patch:
+		return v.patch < w.patch
+	case v.pre == "" || w.pre == "":
+		return v.pre != "" && w.pre == ""
+	}
+	return v.pre < w.pre
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// importersOfAll returns the nodes that import every one of the given
+// packages directly, in sorted order
+func (g *graph) importersOfAll(targets []string) []string {
+	imports := make(map[string]map[string]bool)
+	for _, e := range g.Edges {
+		if imports[e.From] == nil {
+			imports[e.From] = make(map[string]bool)
+		}
+		imports[e.From][e.To] = true
+	}
+	var matches []string
+	for from, imps := range imports {
+		all := true
+		for _, target := range targets {
+			all = all && imps[target]
+		}
+		if all {
+			matches = append(matches, from)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// runQuery prints the packages of the graph matching the query flags
+func runQuery(g *graph) error {
+	if *importsAll == "" {
+		return fmt.Errorf("query needs -imports-all")
+	}
+	for _, id := range g.importersOfAll(sanitizeCSV(*importsAll)) {
+		fmt.Println(id)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+)
+
+// renderDot writes the graph to w in the given format. Without a format the
+// DOT source is written as is; otherwise it is piped through graphviz dot. If
+// dot is not installed, svg is drawn by the built-in layered layout instead.
+// With -validate the DOT source is checked first.
+func renderDot(w io.Writer, g *graph, format string) error {
+	if format == "" && !*validate {
+		out = w
+		err := writeDot(g)
+		out = os.Stdout
+		return err
+	}
+
+	var buf bytes.Buffer
+	out = &buf
+	err := writeDot(g)
+	out = os.Stdout
+	if err != nil {
+		return err
+	}
+	if *validate {
+		if err := validateDot(buf.String()); err != nil {
+			return fmt.Errorf("generated invalid DOT: %s", err)
+		}
+	}
+	if format == "" {
+		_, err := buf.WriteTo(w)
+		return err
+	}
+
+	if dot, err := exec.LookPath("dot"); err == nil {
+		cmd := exec.Command(dot, "-T"+format)
+		cmd.Stdin = &buf
+		cmd.Stdout = w
+		cmd.Stderr = logOutput
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to render with dot: %s", err)
+		}
+		return nil
+	}
+	if format != "svg" {
+		return fmt.Errorf("rendering %s requires graphviz dot in PATH", format)
+	}
+	return writeSVG(w, g)
+}
+
+const (
+	layerHeight = 100
+	nodeHeight  = 36
+	nodeGap     = 24
+	charWidth   = 7
+)
+
+type layoutNode struct {
+	key    string // namespaced id
+	name   string
+	color  string // fill color set by overlays, the node color by default
+	stroke string // outline color set by overlays, the node color by default
+	layer  int
+	x, y   float64
+	width  float64
+	weight float64 // barycenter used for ordering within a layer
+}
+
+// layout assigns every node a layer below all of its importers, breaking
+// cycles at the first edge found, and orders each layer by the barycenter of
+// the neighbours in the layer above. Nodes and edges are keyed by their
+// namespaced id.
+func layout(g *graph) ([]*layoutNode, [][2]string) {
+	var names []string
+	nodes := make(map[string]*layoutNode)
+	for _, n := range g.Nodes {
+		key := ns(g.namespaceOf(n.Namespace), n.ID)
+		names = append(names, key)
+		nodes[key] = &layoutNode{
+			key:    key,
+			name:   n.ID,
+			color:  choose(n.Attrs["fillcolor"] != "", n.Attrs["fillcolor"], n.Color),
+			stroke: choose(n.Attrs["color"] != "", n.Attrs["color"], n.Color),
+			width:  float64(len(n.ID)*charWidth + 20),
+		}
+	}
+	var edges [][2]string
+	importers := make(map[string][]string)
+	for _, e := range g.Edges {
+		namespace := g.namespaceOf(e.Namespace)
+		from, to := ns(namespace, e.From), ns(namespace, e.To)
+		if nodes[from] == nil || nodes[to] == nil {
+			continue
+		}
+		edges = append(edges, [2]string{from, to})
+		importers[to] = append(importers[to], from)
+	}
+
+	// longest path from any package nobody imports
+	state := make(map[string]int) // 0 new, 1 in progress, 2 done
+	var assign func(v string) int
+	assign = func(v string) int {
+		if state[v] != 0 {
+			return nodes[v].layer
+		}
+		state[v] = 1
+		layer := 0
+		for _, u := range importers[v] {
+			if state[u] == 1 {
+				continue // back edge of a cycle
+			}
+			if l := assign(u) + 1; l > layer {
+				layer = l
+			}
+		}
+		nodes[v].layer = layer
+		state[v] = 2
+		return layer
+	}
+
+	var layers [][]*layoutNode
+	for _, name := range names {
+		n := nodes[name]
+		assign(name)
+		for len(layers) <= n.layer {
+			layers = append(layers, nil)
+		}
+		layers[n.layer] = append(layers[n.layer], n)
+	}
+
+	position := func(layer []*layoutNode) {
+		x := 0.0
+		for i, n := range layer {
+			n.weight = float64(i)
+			n.x = x + n.width/2
+			x += n.width + nodeGap
+		}
+	}
+	if len(layers) > 0 {
+		position(layers[0])
+	}
+	for i := 1; i < len(layers); i++ {
+		for _, n := range layers[i] {
+			sum, count := 0.0, 0
+			for _, u := range importers[n.key] {
+				if un := nodes[u]; un.layer < n.layer {
+					sum += un.weight
+					count++
+				}
+			}
+			if count > 0 {
+				n.weight = sum / float64(count)
+			}
+		}
+		sort.SliceStable(layers[i], func(a, b int) bool {
+			return layers[i][a].weight < layers[i][b].weight
+		})
+		position(layers[i])
+	}
+
+	// center the layers on the widest one
+	width := 0.0
+	for _, layer := range layers {
+		if len(layer) > 0 {
+			last := layer[len(layer)-1]
+			if w := last.x + last.width/2; w > width {
+				width = w
+			}
+		}
+	}
+	var all []*layoutNode
+	for i, layer := range layers {
+		shift := 0.0
+		if len(layer) > 0 {
+			last := layer[len(layer)-1]
+			shift = (width - (last.x + last.width/2)) / 2
+		}
+		for _, n := range layer {
+			n.x += shift + nodeGap
+			n.y = float64(i*layerHeight + nodeGap + nodeHeight/2)
+			all = append(all, n)
+		}
+	}
+	return all, edges
+}
+
+// svgColors maps the graphviz colors godepgraph draws with that are not SVG
+// color keywords, or are SVG keywords for another color, like grey
+var svgColors = map[string]string{
+	"darkgoldenrod1": "#ffb90f",
+	"grey":           "#c0c0c0",
+	"grey40":         "#666666",
+}
+
+// svgColor returns the SVG color for a graphviz color name
+func svgColor(name string) string {
+	if c, ok := svgColors[name]; ok {
+		return c
+	}
+	return name
+}
+
+func writeSVG(w io.Writer, g *graph) error {
+	nodes, edges := layout(g)
+	byName := make(map[string]*layoutNode)
+	width, height := 0.0, 0.0
+	for _, n := range nodes {
+		byName[n.key] = n
+		if r := n.x + n.width/2 + nodeGap; r > width {
+			width = r
+		}
+		if b := n.y + nodeHeight/2 + nodeGap; b > height {
+			height = b
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height)
+	fmt.Fprintln(&buf, `<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto"><path d="M0,0 L10,5 L0,10 z"/></marker></defs>`)
+	strokes := make(map[[2]string]string)
+	for _, e := range g.Edges {
+		namespace := g.namespaceOf(e.Namespace)
+		strokes[[2]string{ns(namespace, e.From), ns(namespace, e.To)}] = e.Attrs["color"]
+	}
+	for _, e := range edges {
+		from, to := byName[e[0]], byName[e[1]]
+		stroke := choose(strokes[e] != "", strokes[e], "black")
+		fmt.Fprintf(&buf, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"%s\" marker-end=\"url(#arrow)\"/>\n",
+			from.x, from.y+nodeHeight/2, to.x, to.y-nodeHeight/2, svgColor(stroke))
+	}
+	for _, n := range nodes {
+		fmt.Fprintf(&buf, "<g><title>%s</title><ellipse cx=\"%.1f\" cy=\"%.1f\" rx=\"%.1f\" ry=\"%d\" fill=\"%s\" stroke=\"%s\"/>", html.EscapeString(n.name), n.x, n.y, n.width/2, nodeHeight/2, svgColor(n.color), svgColor(n.stroke))
+		fmt.Fprintf(&buf, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"middle\">%s</text></g>\n", n.x, n.y+4, html.EscapeString(n.name))
+	}
+	fmt.Fprintln(&buf, "</svg>")
+	_, err := buf.WriteTo(w)
+	return err
+}
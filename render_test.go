@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSVGFillAndColors(t *testing.T) {
+	g := &graph{
+		Nodes: []*node{
+			{ID: "example.com/app", Color: "paleturquoise", Attrs: attrs{"fillcolor": "#ff3737"}},
+			{ID: "example.com/app/store", Color: "darkgoldenrod1"},
+			{ID: "example.com/app.Open", Color: "white", Attrs: attrs{"color": "grey40"}},
+		},
+		Edges: []*edge{
+			{From: "example.com/app", To: "example.com/app/store", Attrs: attrs{"color": "grey"}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeSVG(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	for _, want := range []string{
+		`fill="#ff3737" stroke="paleturquoise"`,
+		`fill="#ffb90f" stroke="#ffb90f"`,
+		`fill="white" stroke="#666666"`,
+		`stroke="#c0c0c0" marker-end`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg lacks %s:\n%s", want, svg)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// viewFlags are the flags applyViews reads
+var viewFlags = []string{
+	"metadata", "cut-edges", "fan-in", "rename", "set", "reachable-to", "focus-file", "focus-neighbours",
+	"sample", "contract-chains", "groups", "classify", "classify-exec", "collapse-groups",
+}
+
+// replFlags are the flags the repl can change: the views, the output format
+// and the settings of the subcommands that work on the graph. Filters and
+// overlays that need the sources only apply when scanning.
+var replFlags = append(viewFlags, "format", "render", "anonymize", "validate", "imports-all", "remove", "candidates", "model", "model-diff")
+
+var replHelp = `commands:
+  set <flag> <value>  change a flag, like set focus-file core.focus
+  unset <flag>        reset a flag to its default
+  show                list the flags that differ from their defaults
+  write               write the graph to -repl-out again
+  <subcommand>        run check, conform, freeze, query, stats, modules, why, whatif or split
+  quit                leave the repl
+flags: ` + strings.Join(replFlags, ", ") + `
+`
+
+// withoutViews runs build with the view flags at their defaults, so that the
+// repl gets the whole graph to apply its views to
+func withoutViews(build func() (*graph, error)) (*graph, error) {
+	saved := make(map[string]string)
+	for _, name := range viewFlags {
+		f := flag.Lookup(name)
+		saved[name] = f.Value.String()
+		f.Value.Set(f.DefValue)
+	}
+	defer func() {
+		for name, value := range saved {
+			flag.Set(name, value)
+		}
+	}()
+	return build()
+}
+
+// runREPL reads commands from stdin that change the views and settings of
+// the graph, writing the graph to -repl-out after every change. The packages
+// are scanned only once, as the views are applied to a copy of the graph
+// every time.
+func runREPL(base *graph) error {
+	var whole bytes.Buffer
+	if err := json.NewEncoder(&whole).Encode(base); err != nil {
+		return fmt.Errorf("failed to copy graph: %s", err)
+	}
+	view := func() (*graph, error) {
+		g := new(graph)
+		if err := json.Unmarshal(whole.Bytes(), g); err != nil {
+			return nil, fmt.Errorf("failed to copy graph: %s", err)
+		}
+		if err := g.applyViews(); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	write := func() error {
+		g, err := view()
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(*replOut)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %s", *replOut, err)
+		}
+		if err := writeGraph(f, g); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %s", *replOut, err)
+		}
+		fmt.Printf("wrote %d packages to %s\n", len(g.Nodes), *replOut)
+		return nil
+	}
+	allowed := stringSet(replFlags)
+
+	if err := write(); err != nil {
+		fmt.Printf("error: %s\n", err)
+	}
+	s := bufio.NewScanner(os.Stdin)
+	for fmt.Print("> "); s.Scan(); fmt.Print("> ") {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch cmd, args := fields[0], fields[1:]; {
+		case cmd == "quit" || cmd == "exit":
+			return nil
+		case cmd == "help":
+			fmt.Print(replHelp)
+		case cmd == "show":
+			flag.VisitAll(func(f *flag.Flag) {
+				if allowed[f.Name] && f.Value.String() != f.DefValue {
+					fmt.Printf("-%s=%s\n", f.Name, f.Value)
+				}
+			})
+		case cmd == "write":
+			err = write()
+		case cmd == "set" || cmd == "unset":
+			err = replSet(cmd, args, allowed)
+			if err == nil {
+				err = write()
+			}
+		case replCommands[cmd]:
+			var g *graph
+			if g, err = view(); err != nil {
+				break
+			}
+			var violations bool
+			if violations, err = runGraphCommand(cmd, g); err == nil && violations {
+				fmt.Println("check failed")
+			}
+		default:
+			err = fmt.Errorf("unknown command %q, try help", cmd)
+		}
+		if err != nil {
+			fmt.Printf("error: %s\n", err)
+		}
+	}
+	fmt.Println()
+	return s.Err()
+}
+
+// replCommands are the subcommands the repl runs on the current view
+var replCommands = map[string]bool{
+	"check":   true,
+	"conform": true,
+	"freeze":  true,
+	"query":   true,
+	"stats":   true,
+	"modules": true,
+	"why":     true,
+	"whatif":  true,
+	"split":   true,
+}
+
+// replSet handles set and unset
+func replSet(cmd string, args []string, allowed map[string]bool) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s needs a flag name", cmd)
+	}
+	name := strings.TrimLeft(args[0], "-")
+	if !allowed[name] {
+		return fmt.Errorf("-%s cannot be changed in the repl, only %s", name, strings.Join(replFlags, ", "))
+	}
+	f := flag.Lookup(name)
+	value := f.DefValue
+	if cmd == "set" {
+		switch {
+		case len(args) > 1:
+			value = strings.Join(args[1:], " ")
+		case isBoolFlag(f):
+			value = "true"
+		default:
+			return fmt.Errorf("set -%s needs a value", name)
+		}
+	}
+	if err := f.Value.Set(value); err != nil {
+		return fmt.Errorf("invalid value %q for -%s: %s", value, name, err)
+	}
+	return nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// sample reduces the graph to at most n nodes: the roots, then the most
+// imported packages, and a sample of the packages importing nothing for the
+// rest. Nodes are annotated with the number of their imports left out. The
+// sample is the same on every run.
+func (g *graph) sample(n int) {
+	if len(g.Nodes) <= n {
+		return
+	}
+	importers := make(map[string]int)
+	imports := make(map[string]int)
+	for _, e := range g.Edges {
+		importers[e.To]++
+		imports[e.From]++
+	}
+
+	keep := make(map[string]bool)
+	for _, root := range g.Roots {
+		if len(keep) < n {
+			keep[root] = true
+		}
+	}
+	var hubs, leaves []*node
+	for _, nd := range g.Nodes {
+		if keep[nd.ID] {
+			continue
+		}
+		if imports[nd.ID] == 0 {
+			leaves = append(leaves, nd)
+		} else {
+			hubs = append(hubs, nd)
+		}
+	}
+	sort.SliceStable(hubs, func(i, j int) bool { return importers[hubs[i].ID] > importers[hubs[j].ID] })
+	hash := func(id string) uint32 {
+		h := fnv.New32a()
+		h.Write([]byte(id))
+		return h.Sum32()
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return hash(leaves[i].ID) < hash(leaves[j].ID) })
+
+	// half of what is left goes to hubs, unless there are not enough leaves
+	budget := n - len(keep)
+	hubBudget := budget - budget/2
+	if len(leaves) < budget/2 {
+		hubBudget = budget - len(leaves)
+	}
+	for _, nd := range hubs {
+		if hubBudget == 0 {
+			break
+		}
+		keep[nd.ID] = true
+		hubBudget--
+	}
+	for _, nd := range leaves {
+		if len(keep) == n {
+			break
+		}
+		keep[nd.ID] = true
+	}
+
+	omitted := make(map[string]int)
+	var edges []*edge
+	for _, e := range g.Edges {
+		if keep[e.From] && keep[e.To] {
+			edges = append(edges, e)
+		} else if keep[e.From] {
+			omitted[e.From]++
+		}
+	}
+	var nodes []*node
+	for _, nd := range g.Nodes {
+		if !keep[nd.ID] {
+			continue
+		}
+		if omitted[nd.ID] > 0 {
+			if nd.Attrs == nil {
+				nd.Attrs = attrs{}
+			}
+			nd.Attrs.add(attrs{"label": fmt.Sprintf("(+%d imports not shown)", omitted[nd.ID])})
+		}
+		nodes = append(nodes, nd)
+	}
+	logger.Warn("showing a sample of the graph", "shown", len(nodes), "omitted", len(g.Nodes)-len(nodes))
+	g.Nodes, g.Edges = nodes, edges
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"sort"
+	"strings"
+)
+
+// scopedRule is a filter that only applies to the imports of the packages
+// beneath a prefix, see -scoped
+type scopedRule struct {
+	scope string
+	// noTests leaves out the test imports, noImports all imports
+	noTests, noImports bool
+	// ignore holds the import paths and glob patterns left out
+	ignore []string
+}
+
+// scopedRules holds the rules of -scoped
+var scopedRules []*scopedRule
+
+// loadScoped reads a file of "prefix = action, ..." lines. The prefix may be
+// a glob pattern. The actions are no-tests, no-imports and ignore followed
+// by an import path or pattern.
+func loadScoped(name string) ([]*scopedRule, error) {
+	config, err := readConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*scopedRule
+	for scope, actions := range config {
+		r := &scopedRule{scope: scope}
+		for _, action := range strings.Split(actions, ",") {
+			fields := strings.Fields(action)
+			switch {
+			case len(fields) == 1 && fields[0] == "no-tests":
+				r.noTests = true
+			case len(fields) == 1 && fields[0] == "no-imports":
+				r.noImports = true
+			case len(fields) == 2 && fields[0] == "ignore":
+				r.ignore = append(r.ignore, fields[1])
+			default:
+				return nil, fmt.Errorf("%s: unknown action %q for %s, expected no-tests, no-imports or ignore <pattern>", name, strings.TrimSpace(action), scope)
+			}
+		}
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].scope < rules[j].scope })
+	return rules, nil
+}
+
+// scopedTests reports whether the rules for the package let its test imports
+// be followed
+func scopedTests(importPath string) bool {
+	for _, r := range scopedRules {
+		if r.noTests && matchesPrefix(importPath, r.scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// scopedImports returns the imports of pkg that the rules for it keep
+func scopedImports(pkg *build.Package, imps []string) []string {
+	var rules []*scopedRule
+	for _, r := range scopedRules {
+		if matchesPrefix(pkg.ImportPath, r.scope) {
+			if r.noImports {
+				return nil
+			}
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return imps
+	}
+	var kept []string
+	for _, imp := range imps {
+		if reason := scopedIgnore(rules, imp); reason != "" {
+			logger.Debug("left out import", "pkg", pkg.ImportPath, "imp", imp, "reason", reason)
+			continue
+		}
+		kept = append(kept, imp)
+	}
+	return kept
+}
+
+func scopedIgnore(rules []*scopedRule, imp string) string {
+	for _, r := range rules {
+		for _, p := range r.ignore {
+			if p == imp || isGlob(p) && matchGlob(p, imp) {
+				return fmt.Sprintf("%s ignored under %s by -scoped", p, r.scope)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reachable returns the nodes reachable from the given one along the edges
+// of the graph, including itself
+func (g *graph) reachable(from string) map[string]bool {
+	out := make(map[string][]string)
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e.To)
+	}
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range out[v] {
+			if !seen[w] {
+				seen[w] = true
+				queue = append(queue, w)
+			}
+		}
+	}
+	return seen
+}
+
+// applySet reduces the graph to the dependencies of its roots selected by
+// -set: those shared by all roots, those of any root, or those of one root
+// that no other root depends on. The roots of interest are kept for context.
+func (g *graph) applySet(set string) error {
+	perRoot := make(map[string]map[string]bool)
+	for _, root := range g.Roots {
+		perRoot[root] = g.reachable(root)
+	}
+
+	keep := make(map[string]bool)
+	switch {
+	case set == "union":
+		for _, reached := range perRoot {
+			for id := range reached {
+				keep[id] = true
+			}
+		}
+	case set == "intersection":
+		for _, n := range g.Nodes {
+			shared := true
+			for _, reached := range perRoot {
+				shared = shared && reached[n.ID]
+			}
+			keep[n.ID] = shared
+		}
+		for _, root := range g.Roots {
+			keep[root] = true
+		}
+	case strings.HasPrefix(set, "unique:"):
+		root := strings.TrimPrefix(set, "unique:")
+		if perRoot[root] == nil {
+			return fmt.Errorf("-set %s: %s is not a root package", set, root)
+		}
+		for id := range perRoot[root] {
+			keep[id] = true
+			for other, reached := range perRoot {
+				if other != root && reached[id] {
+					keep[id] = false
+				}
+			}
+		}
+		keep[root] = true
+	default:
+		return fmt.Errorf("unknown -set %q, must be intersection, union or unique:<pkg>", set)
+	}
+
+	g.keep(keep)
+	return nil
+}
+
+// reachableTo reduces the graph to the nodes from which any of the targets
+// can be reached along the edges, and the targets themselves
+func (g *graph) reachableTo(targets []string) {
+	in := make(map[string][]string)
+	for _, e := range g.Edges {
+		in[e.To] = append(in[e.To], e.From)
+	}
+	found := make(map[string]bool)
+	for _, n := range g.Nodes {
+		found[n.ID] = true
+	}
+	seen := make(map[string]bool)
+	var queue []string
+	for _, target := range targets {
+		if !found[target] {
+			logger.Warn("-reachable-to package is not in the graph", "pkg", target)
+			continue
+		}
+		seen[target] = true
+		queue = append(queue, target)
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, u := range in[v] {
+			if !seen[u] {
+				seen[u] = true
+				queue = append(queue, u)
+			}
+		}
+	}
+	g.keep(seen)
+}
+
+// keep reduces the graph to the given nodes and the edges among them
+func (g *graph) keep(keep map[string]bool) {
+	var nodes []*node
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			nodes = append(nodes, n)
+		}
+	}
+	var edges []*edge
+	for _, e := range g.Edges {
+		if keep[e.From] && keep[e.To] {
+			edges = append(edges, e)
+		}
+	}
+	g.Nodes, g.Edges = nodes, edges
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// counts returns the number of nodes and edges of the graph
+func (g *graph) counts() [2]int {
+	return [2]int{len(g.Nodes), len(g.Edges)}
+}
+
+// note records a simplification of the graph, for -simplified
+func (g *graph) note(format string, args ...interface{}) {
+	g.Simplifications = append(g.Simplifications, fmt.Sprintf(format, args...))
+}
+
+// noteHidden records how many nodes and edges a view hid, given the counts
+// from before it applied
+func (g *graph) noteHidden(view string, before [2]int) {
+	var hidden []string
+	if n := before[0] - len(g.Nodes); n > 0 {
+		hidden = append(hidden, fmt.Sprintf("%d of %d %s", n, before[0], choose(before[0] == 1, "package", "packages")))
+	}
+	if n := before[1] - len(g.Edges); n > 0 {
+		hidden = append(hidden, fmt.Sprintf("%d of %d %s", n, before[1], choose(before[1] == 1, "import", "imports")))
+	}
+	if len(hidden) > 0 {
+		g.note("%s hid %s", view, strings.Join(hidden, " and "))
+	}
+}
+
+// reportSimplifications logs what the filters and views left out of the
+// graph at info level, so that readers of a simplified diagram know what
+// detail is missing
+func reportSimplifications(g *graph) {
+	if len(g.Simplifications) == 0 {
+		logger.Info("did not simplify the graph")
+		return
+	}
+	for _, s := range g.Simplifications {
+		logger.Info("simplified the graph", "note", s)
+	}
+}
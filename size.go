@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+)
+
+// packageSize counts the Go files of a package, tests left out, and their
+// lines that are not blank
+func packageSize(pkg *build.Package) (files, lines int, err error) {
+	for _, name := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+		data, err := os.ReadFile(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to count lines: %s", err)
+		}
+		files++
+		s := bufio.NewScanner(bytes.NewReader(data))
+		s.Buffer(nil, len(data)+1)
+		for s.Scan() {
+			if len(bytes.TrimSpace(s.Bytes())) > 0 {
+				lines++
+			}
+		}
+	}
+	return files, lines, nil
+}
+
+func sizeAttrs(files, lines int) attrs {
+	return attrs{"label": fmt.Sprintf("(%d %s, %d %s)", files, choose(files == 1, "file", "files"), lines, choose(lines == 1, "line", "lines"))}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshotLayout names snapshot files so that they sort chronologically
+const snapshotLayout = "2006-01-02"
+
+// writeSnapshot stores the graph as today's snapshot in dir. A second
+// snapshot on the same day replaces the first.
+func writeSnapshot(dir string, g *graph) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %s", err)
+	}
+	name := filepath.Join(dir, time.Now().Format(snapshotLayout)+".json")
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %s", err)
+	}
+	if err := writeJSON(f, g); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write snapshot: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %s", err)
+	}
+	logger.Info("wrote snapshot", "file", name)
+	return nil
+}
+
+// snapshots returns the snapshot files in dir, oldest first
+func snapshots(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// runReport prints the changes between the two latest snapshots
+func runReport(dir string) error {
+	files, err := snapshots(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) < 2 {
+		return fmt.Errorf("need at least two snapshots in %s, found %d", dir, len(files))
+	}
+	prevFile, lastFile := files[len(files)-2], files[len(files)-1]
+	prev, err := readGraph(prevFile)
+	if err != nil {
+		return err
+	}
+	last, err := readGraph(lastFile)
+	if err != nil {
+		return err
+	}
+
+	if *diffSummary == "" {
+		fmt.Printf("Dependency changes from %s to %s\n\n", snapshotName(prevFile), snapshotName(lastFile))
+	}
+	return printDiff(diffGraphs(prev, last), last.ownModule())
+}
+
+func snapshotName(file string) string {
+	base := filepath.Base(file)
+	return base[:len(base)-len(filepath.Ext(base))]
+}
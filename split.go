@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// boundary is a candidate for splitting a directory tree of the own module
+// off into a module of its own
+type boundary struct {
+	prefix   string
+	packages int
+	// in holds the imports of the tree from the rest of the own module, out
+	// the imports the tree makes of the rest
+	in, out []*edge
+}
+
+// boundaries returns every directory tree of the own module that holds
+// packages, with the imports crossing its border, the trees with the fewest
+// crossing imports first. Only imports among the own packages count, the
+// external and standard library ones stay as they are in a split. This is a
+// ranking of the trees, not a minimum cut over any set of packages: a cut
+// that is no directory tree cannot become a module without moving packages.
+func (g *graph) boundaries() []*boundary {
+	own := g.ownModule()
+	mine := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Module == own && own != "" && !n.Stdlib && !n.Ghost && !n.Test && n.Error == "" {
+			mine[n.ID] = true
+		}
+	}
+
+	trees := make(map[string]*boundary)
+	for id := range mine {
+		rest := strings.TrimPrefix(id, own+"/")
+		if rest == id {
+			continue
+		}
+		elems := strings.Split(rest, "/")
+		for i := range elems {
+			prefix := own + "/" + strings.Join(elems[:i+1], "/")
+			if trees[prefix] == nil {
+				trees[prefix] = &boundary{prefix: prefix}
+			}
+			trees[prefix].packages++
+		}
+	}
+
+	var list []*boundary
+	for _, b := range trees {
+		if b.packages == len(mine) {
+			continue
+		}
+		// a tree holding the same packages as its parent adds nothing
+		if parent := trees[path.Dir(b.prefix)]; parent != nil && parent.packages == b.packages {
+			continue
+		}
+		for _, e := range g.Edges {
+			if !mine[e.From] || !mine[e.To] {
+				continue
+			}
+			from, to := inTree(e.From, b.prefix), inTree(e.To, b.prefix)
+			switch {
+			case to && !from:
+				b.in = append(b.in, e)
+			case from && !to:
+				b.out = append(b.out, e)
+			}
+		}
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		ci, cj := len(list[i].in)+len(list[i].out), len(list[j].in)+len(list[j].out)
+		if ci != cj {
+			return ci < cj
+		}
+		if list[i].packages != list[j].packages {
+			return list[i].packages > list[j].packages
+		}
+		return list[i].prefix < list[j].prefix
+	})
+	return list
+}
+
+// printBoundaries prints the best candidates for a module split, each with
+// the crossing imports that the split has to deal with
+func printBoundaries(g *graph) error {
+	list := g.boundaries()
+	if *splitCandidates >= 0 && len(list) > *splitCandidates {
+		list = list[:*splitCandidates]
+	}
+	for _, b := range list {
+		crossing := len(b.in) + len(b.out)
+		fmt.Printf("%s: %d %s, %d crossing %s\n", b.prefix,
+			b.packages, choose(b.packages == 1, "package", "packages"),
+			crossing, choose(crossing == 1, "import", "imports"))
+		if len(b.in) > 0 && len(b.out) > 0 {
+			fmt.Println("    imports go both ways, the modules would depend on each other")
+		}
+		for _, e := range b.in {
+			fmt.Printf("    in:  %s -> %s\n", e.From, e.To)
+		}
+		for _, e := range b.out {
+			fmt.Printf("    out: %s -> %s\n", e.From, e.To)
+		}
+	}
+	return nil
+}
+
+// inTree reports whether an import path is the prefix or below it
+func inTree(importPath, prefix string) bool {
+	return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+}
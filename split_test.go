@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestBoundariesRankTreesByCrossingImports(t *testing.T) {
+	g := &graph{Roots: []string{"example.com/app/cmd"}}
+	for _, id := range []string{"example.com/app/cmd", "example.com/app/api", "example.com/app/billing", "example.com/app/billing/invoice", "example.com/app/store"} {
+		g.Nodes = append(g.Nodes, &node{ID: id, Module: "example.com/app"})
+	}
+	g.Nodes = append(g.Nodes, &node{ID: "github.com/lib/pq", Module: "github.com/lib/pq"})
+	for _, e := range [][2]string{
+		{"example.com/app/cmd", "example.com/app/api"},
+		{"example.com/app/api", "example.com/app/billing"},
+		{"example.com/app/api", "example.com/app/store"},
+		{"example.com/app/billing", "example.com/app/billing/invoice"},
+		{"example.com/app/billing/invoice", "example.com/app/store"},
+		{"example.com/app/store", "github.com/lib/pq"},
+	} {
+		g.Edges = append(g.Edges, &edge{From: e[0], To: e[1]})
+	}
+	tests := []struct {
+		prefix    string
+		packages  int
+		ins, outs int
+	}{
+		{"example.com/app/cmd", 1, 0, 1},
+		{"example.com/app/billing", 2, 1, 1},
+		{"example.com/app/billing/invoice", 1, 1, 1},
+		{"example.com/app/store", 1, 2, 0},
+		{"example.com/app/api", 1, 1, 2},
+	}
+	list := g.boundaries()
+	if len(list) != len(tests) {
+		t.Fatalf("got %d trees, want %d", len(list), len(tests))
+	}
+	for i, tt := range tests {
+		b := list[i]
+		if b.prefix != tt.prefix || b.packages != tt.packages || len(b.in) != tt.ins || len(b.out) != tt.outs {
+			t.Errorf("tree %d is %s with %d packages, %d in and %d out, want %s with %d, %d and %d",
+				i, b.prefix, b.packages, len(b.in), len(b.out), tt.prefix, tt.packages, tt.ins, tt.outs)
+		}
+	}
+}
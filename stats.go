@@ -0,0 +1,41 @@
+package main
+
+import "fmt"
+
+// printStats prints the size and shape of the graph
+func printStats(g *graph) error {
+	var stdlib, errs, ghosts, lines int
+	for _, n := range g.Nodes {
+		if !n.Stdlib {
+			lines += n.Lines
+		}
+		switch {
+		case n.Error != "":
+			errs++
+		case n.Ghost:
+			ghosts++
+		case n.Stdlib:
+			stdlib++
+		}
+	}
+	maxDepth := 0
+	for _, root := range g.Roots {
+		if d := g.depth(root); d > maxDepth {
+			maxDepth = d
+		}
+	}
+	fmt.Printf("packages:         %d\n", len(g.Nodes)-errs-ghosts)
+	fmt.Printf("standard library: %d\n", stdlib)
+	fmt.Printf("imports:          %d\n", len(g.Edges))
+	fmt.Printf("external modules: %d\n", len(g.externalModules()))
+	fmt.Printf("lines of code:    %d\n", lines)
+	fmt.Printf("depth:            %d\n", maxDepth)
+	fmt.Printf("cycles:           %d\n", len(g.cycles()))
+	if ghosts > 0 {
+		fmt.Printf("filtered:         %d\n", ghosts)
+	}
+	if errs > 0 {
+		fmt.Printf("unresolved:       %d\n", errs)
+	}
+	return nil
+}
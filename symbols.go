@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// symbolUsage holds the exported package-level identifiers an importer uses
+// from each of its imports, sorted, keyed by importer and import
+type symbolUsage map[[2]string][]string
+
+// loadSymbolUsage type checks every visible package outside the standard
+// library and records which identifiers of its imports it refers to.
+// Packages that fail to type check are left out with a warning.
+func loadSymbolUsage() (symbolUsage, error) {
+	fset := token.NewFileSet()
+	// the source importer caches the packages it checked, so every
+	// dependency is only type checked once
+	imp := importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)
+	usage := make(symbolUsage)
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if pkg.Goroot {
+			continue
+		}
+		var files []*ast.File
+		for _, name := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+			f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %s", name, err)
+			}
+			files = append(files, f)
+		}
+
+		info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+		conf := types.Config{
+			Importer:    importerAt{imp, pkg.Dir},
+			FakeImportC: true,
+			Error:       func(error) {}, // keep what could be checked
+		}
+		if _, err := conf.Check(pkg.ImportPath, fset, files, info); err != nil && len(info.Uses) == 0 {
+			logger.Warn("failed to type check package", "pkg", pkgName, "err", err)
+			continue
+		}
+
+		used := make(map[string]map[string]bool)
+		for _, obj := range info.Uses {
+			if obj.Pkg() == nil || obj.Pkg().Path() == pkg.ImportPath || !obj.Exported() {
+				continue
+			}
+			// only package-level identifiers, not fields and methods
+			if obj.Pkg().Scope().Lookup(obj.Name()) != obj {
+				continue
+			}
+			path := obj.Pkg().Path()
+			if used[path] == nil {
+				used[path] = make(map[string]bool)
+			}
+			used[path][obj.Name()] = true
+		}
+		for path, names := range used {
+			var list []string
+			for name := range names {
+				list = append(list, name)
+			}
+			sort.Strings(list)
+			usage[[2]string{pkgName, path}] = list
+		}
+	}
+	return usage, nil
+}
+
+// importerAt resolves imports relative to the directory of the importing
+// package, which matters for vendored packages
+type importerAt struct {
+	types.ImporterFrom
+	dir string
+}
+
+func (i importerAt) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, i.dir, 0)
+}
+
+// edgeAttrs labels the edge with the number of identifiers used across it
+func (u symbolUsage) edgeAttrs(source, dest string) attrs {
+	names, ok := u[[2]string{source, dest}]
+	if !ok {
+		return nil
+	}
+	return attrs{
+		"label":   fmt.Sprintf("%d", len(names)),
+		"tooltip": strings.Join(names, ", "),
+	}
+}
+
+// expand replaces the edges to a package by edges to the identifiers each
+// importer uses from it. The package and its identifiers are boxed together,
+// so the importers and what they use of the package face each other.
+func (g *graph) expand(pkgName string, usage symbolUsage) {
+	var target *node
+	for _, n := range g.Nodes {
+		if n.ID == pkgName {
+			target = n
+		}
+	}
+	if target == nil {
+		logger.Warn("cannot expand package not in the graph", "pkg", pkgName)
+		return
+	}
+	target.Group = pkgName
+
+	seen := make(map[string]bool)
+	var edges []*edge
+	for _, e := range g.Edges {
+		names, ok := usage[[2]string{e.From, e.To}]
+		if e.To != pkgName || !ok {
+			edges = append(edges, e)
+			continue
+		}
+		for _, name := range names {
+			id := pkgName + "." + name
+			if !seen[id] {
+				seen[id] = true
+				g.Nodes = append(g.Nodes, &node{
+					ID:     id,
+					Module: target.Module,
+					Stdlib: target.Stdlib,
+					Symbol: true,
+					Group:  pkgName,
+					Color:  "white",
+					Attrs:  attrs{"shape": "box", "fontsize": "10", "color": "grey40"},
+				})
+			}
+			edges = append(edges, &edge{Namespace: e.Namespace, From: e.From, To: id, Attrs: e.Attrs})
+		}
+	}
+	g.Edges = edges
+}
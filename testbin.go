@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// testedPackages returns the visible packages that have tests, and thus a
+// test binary, in sorted order
+func testedPackages() []string {
+	var tested []string
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if testsEnabled(pkg) && len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) > 0 {
+			tested = append(tested, pkgName)
+		}
+	}
+	return tested
+}
+
+// testBinaries reduces a graph scanned with test imports to the packages
+// linked into the test binaries of the given packages. Every binary gets a
+// node of its own, named like the binary go test builds, importing the
+// package under test and its external test package. Test imports are only
+// followed from the package under test, since the tests of its dependencies
+// are not linked in.
+func (g *graph) testBinaries(tested []string) {
+	byID := make(map[string]*node)
+	for _, n := range g.Nodes {
+		byID[n.ID] = n
+	}
+	out := make(map[string][]*edge)
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e)
+	}
+
+	keep := make(map[string]bool)
+	keepEdge := make(map[*edge]bool)
+	var binaries []*node
+	var binaryEdges []*edge
+	for _, pkgName := range tested {
+		underTest := map[string]bool{pkgName: true, pkgName + "_test": true}
+		linked := make(map[string]bool)
+		var visit func(v string)
+		visit = func(v string) {
+			if linked[v] || byID[v] == nil {
+				return
+			}
+			linked[v] = true
+			for _, e := range out[v] {
+				if !e.Test || underTest[e.From] {
+					keepEdge[e] = true
+					visit(e.To)
+				}
+			}
+		}
+		id := pkgName + ".test"
+		for start := range underTest {
+			if byID[start] != nil {
+				visit(start)
+				binaryEdges = append(binaryEdges, &edge{From: id, To: start, Test: true, Attrs: attrs{"style": "dashed"}})
+			}
+		}
+		for v := range linked {
+			keep[v] = true
+		}
+		binaries = append(binaries, &node{
+			ID:    id,
+			Color: "gold",
+			Attrs: attrs{
+				"shape":   "box3d",
+				"label":   fmt.Sprintf("(%d packages)", len(linked)),
+				"tooltip": fmt.Sprintf("test binary of %s, linking %d packages", pkgName, len(linked)),
+			},
+		})
+		logger.Info("test binary", "pkg", pkgName, "packages", len(linked))
+	}
+
+	var nodes []*node
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			nodes = append(nodes, n)
+		}
+	}
+	var edges []*edge
+	for _, e := range g.Edges {
+		if keepEdge[e] {
+			edges = append(edges, e)
+		}
+	}
+	sort.SliceStable(binaryEdges, func(i, j int) bool {
+		if binaryEdges[i].From != binaryEdges[j].From {
+			return binaryEdges[i].From < binaryEdges[j].From
+		}
+		return binaryEdges[i].To < binaryEdges[j].To
+	})
+	g.Nodes = append(binaries, nodes...)
+	g.Edges = append(binaryEdges, edges...)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"go/build"
+	"strings"
+)
+
+// testsEnabled reports whether the test imports of pkg are followed. Only the
+// tests of packages in the base path are, the tests of dependencies are of no
+// concern to the graph.
+func testsEnabled(pkg *build.Package) bool {
+	return *withTests && !pkg.Goroot && strings.HasPrefix(pkg.ImportPath, basePath) && scopedTests(pkg.ImportPath)
+}
+
+// imports returns the imports of pkg, followed by those only some
+// -constraints make and those only its in-package tests make if test imports
+// are enabled
+func imports(pkg *build.Package) []string {
+	extra := conditionalImports(pkg)
+	if testsEnabled(pkg) {
+		extra = append(extra, testImports(pkg)...)
+	}
+	if len(extra) == 0 {
+		return scopedImports(pkg, pkg.Imports)
+	}
+	return scopedImports(pkg, append(append([]string(nil), pkg.Imports...), extra...))
+}
+
+// testImports returns the imports of the in-package tests of pkg that the
+// package itself does not make
+func testImports(pkg *build.Package) []string {
+	regular := make(map[string]bool)
+	for _, imp := range pkg.Imports {
+		regular[imp] = true
+	}
+	var imps []string
+	for _, imp := range pkg.TestImports {
+		if !regular[imp] {
+			imps = append(imps, imp)
+		}
+	}
+	return imps
+}
+
+// isTestImport reports whether imp is only imported by the tests of pkg
+func isTestImport(pkg *build.Package, imp string) bool {
+	for _, i := range pkg.Imports {
+		if i == imp {
+			return false
+		}
+	}
+	if _, ok := conditional[pkg.ImportPath][imp]; ok {
+		return false
+	}
+	return testsEnabled(pkg)
+}
+
+// addExternalTests adds a node for the external test package (package foo_test)
+// of every visible package that has one, connected to its imports. It usually
+// imports the package it tests, which attaches it there.
+func (g *graph) addExternalTests() {
+	for _, pkgName := range visiblePackages() {
+		pkg := pkgs[pkgName]
+		if !testsEnabled(pkg) || len(pkg.XTestGoFiles) == 0 {
+			continue
+		}
+		id := pkgName + "_test"
+		g.Nodes = append(g.Nodes, &node{
+			ID:     id,
+			Module: moduleOf(pkg),
+			Test:   true,
+			Color:  "lightyellow",
+			Attrs:  attrs{"shape": "box", "style": "filled,dashed", "tooltip": "external tests of " + pkgName},
+		})
+		for _, imp := range scopedImports(pkg, pkg.XTestImports) {
+			if impPkg := pkgs[imp]; impPkg != nil && !isIgnored(impPkg) {
+				g.Edges = append(g.Edges, &edge{From: id, To: imp, Test: true, Attrs: attrs{"style": "dashed"}})
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+)
+
+// timelapseFrame is one graph of the time-lapse
+type timelapseFrame struct {
+	Label   string
+	Summary string
+	Graph   template.HTML
+}
+
+var timelapseTemplate = template.Must(template.New("timelapse").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+#frame { width: 30em; }
+.summary { color: grey; }
+.frame { display: none; margin: 1em 0; overflow: auto; }
+.frame.shown { display: block; }
+</style></head>
+<body><h1>{{.Title}}</h1>
+<p><button id="play">play</button>
+<input type="range" id="frame" min="0" max="0" value="0">
+<strong id="label"></strong> <span class="summary" id="summary"></span></p>
+{{range .Frames}}<div class="frame" data-label="{{.Label}}" data-summary="{{.Summary}}">{{.Graph}}</div>
+{{end}}<script>
+var frames = document.querySelectorAll(".frame");
+var slider = document.getElementById("frame");
+slider.max = frames.length - 1;
+function show(i) {
+	frames.forEach(function (f, j) { f.classList.toggle("shown", i == j); });
+	slider.value = i;
+	document.getElementById("label").textContent = frames[i].dataset.label;
+	document.getElementById("summary").textContent = frames[i].dataset.summary;
+}
+slider.oninput = function () { show(+slider.value); };
+var timer = null;
+document.getElementById("play").onclick = function () {
+	if (timer) {
+		clearInterval(timer);
+		timer = null;
+		this.textContent = "play";
+		return;
+	}
+	this.textContent = "pause";
+	if (+slider.value == frames.length - 1) {
+		show(0);
+	}
+	timer = setInterval(function () {
+		if (+slider.value == frames.length - 1) {
+			document.getElementById("play").click();
+			return;
+		}
+		show(+slider.value + 1);
+	}, {{.Interval}});
+};
+show(0);
+</script>
+</body></html>
+`))
+
+// runTimelapse prints an HTML page with a slider stepping through the given
+// graphs, or the snapshots in dir, oldest first. The views apply to every
+// graph, and packages and imports new since the previous graph are drawn in
+// green.
+func runTimelapse(files []string, dir string) error {
+	if len(files) == 0 {
+		all, err := snapshots(dir)
+		if err != nil {
+			return err
+		}
+		files = all
+	}
+	if len(files) < 2 {
+		return fmt.Errorf("need at least two graphs for a time-lapse, found %d", len(files))
+	}
+
+	var frames []*timelapseFrame
+	var prev *graph
+	title := "Time-lapse"
+	for _, name := range files {
+		g, err := readGraph(name)
+		if err != nil {
+			return err
+		}
+		if err := g.applyViews(); err != nil {
+			return err
+		}
+		frame := &timelapseFrame{Label: snapshotName(name)}
+		if prev != nil {
+			d := diffGraphs(prev, g)
+			added := stringSet(d.AddedNodes)
+			for _, n := range g.Nodes {
+				if added[n.ID] {
+					n.Attrs = addAttrs(n.Attrs, attrs{"color": "forestgreen", "penwidth": "3"})
+				}
+			}
+			addedEdges := stringSet(d.AddedEdges)
+			for _, e := range g.Edges {
+				if addedEdges[e.From+" -> "+e.To] {
+					e.Attrs = addAttrs(e.Attrs, attrs{"color": "forestgreen", "penwidth": "2"})
+				}
+			}
+			frame.Summary = fmt.Sprintf("%+d packages, %+d imports, %+d external modules",
+				len(d.AddedNodes)-len(d.RemovedNodes), len(d.AddedEdges)-len(d.RemovedEdges), len(d.AddedModules)-len(d.RemovedModules))
+		} else {
+			frame.Summary = fmt.Sprintf("%d packages, %d imports, %d external modules", len(g.Nodes), len(g.Edges), len(g.externalModules()))
+		}
+		if g.Namespace != "" {
+			title = "Time-lapse of " + g.Namespace
+		}
+		prev = g
+
+		var svg bytes.Buffer
+		if err := renderDot(&svg, g, "svg"); err != nil {
+			return err
+		}
+		frame.Graph = template.HTML(svg.String())
+		frames = append(frames, frame)
+	}
+
+	var page bytes.Buffer
+	if err := timelapseTemplate.Execute(&page, struct {
+		Title    string
+		Frames   []*timelapseFrame
+		Interval int
+	}{title, frames, *frameInterval}); err != nil {
+		return err
+	}
+	_, err := page.WriteTo(os.Stdout)
+	return err
+}
+
+// addAttrs merges b into a, which may be nil
+func addAttrs(a, b attrs) attrs {
+	if a == nil {
+		a = attrs{}
+	}
+	a.add(b)
+	return a
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// dotToken is a token of the DOT language: an ID, which quoted strings,
+// numerals and HTML strings all are, or a punctuation mark
+type dotToken struct {
+	id   bool
+	text string
+	line int
+}
+
+// lexDot splits DOT source into tokens, dropping comments
+func lexDot(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//") || (c == '#' && (i == 0 || src[i-1] == '\n')):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{text: src[i : i+2], line: line})
+			i += 2
+		case strings.IndexByte("{}[]=;,:", c) >= 0:
+			tokens = append(tokens, dotToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			start, j := line, i+1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					line++
+				}
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			tokens = append(tokens, dotToken{id: true, text: src[i : j+1], line: start})
+			i = j + 1
+		case c == '<':
+			start, depth, j := line, 0, i
+			for ; j < len(src); j++ {
+				if src[j] == '<' {
+					depth++
+				} else if src[j] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				} else if src[j] == '\n' {
+					line++
+				}
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated HTML string", start)
+			}
+			tokens = append(tokens, dotToken{id: true, text: src[i : j+1], line: start})
+			i = j + 1
+		case c == '_' || c == '-' || c == '.' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || src[j] >= 0x80 || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || (j == i && src[j] == '-')) {
+				j++
+			}
+			tokens = append(tokens, dotToken{id: true, text: src[i:j], line: line})
+			i = j
+		case c == '+':
+			// concatenation of quoted strings, "a" + "b"
+			tokens = append(tokens, dotToken{text: "+", line: line})
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	return tokens, nil
+}
+
+// dotParser checks tokens against the DOT grammar
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+	edgeOp string
+}
+
+func (p *dotParser) peek() dotToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	line := 0
+	if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return dotToken{text: "end of input", line: line}
+}
+
+func (p *dotParser) is(text string) bool {
+	t := p.peek()
+	return !t.id && t.text == text || t.id && strings.EqualFold(t.text, text) && isDotKeyword(text)
+}
+
+func (p *dotParser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf("expected %s", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *dotParser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	return fmt.Errorf("line %d: %s, found %s", t.line, fmt.Sprintf(format, args...), t.text)
+}
+
+func isDotKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "strict", "graph", "digraph", "node", "edge", "subgraph":
+		return true
+	}
+	return false
+}
+
+// id consumes an ID, including concatenated quoted strings
+func (p *dotParser) id() error {
+	t := p.peek()
+	if !t.id || isDotKeyword(t.text) {
+		return p.errorf("expected an ID")
+	}
+	p.pos++
+	for p.is("+") && strings.HasPrefix(t.text, `"`) {
+		p.pos++
+		if next := p.peek(); !next.id || !strings.HasPrefix(next.text, `"`) {
+			return p.errorf("expected a quoted string after +")
+		}
+		p.pos++
+	}
+	return nil
+}
+
+func (p *dotParser) graph() error {
+	if p.is("strict") {
+		p.pos++
+	}
+	switch {
+	case p.is("digraph"):
+		p.edgeOp = "->"
+	case p.is("graph"):
+		p.edgeOp = "--"
+	default:
+		return p.errorf("expected graph or digraph")
+	}
+	p.pos++
+	if !p.is("{") {
+		if err := p.id(); err != nil {
+			return err
+		}
+	}
+	if err := p.body(); err != nil {
+		return err
+	}
+	if p.pos < len(p.tokens) {
+		return p.errorf("expected end of input")
+	}
+	return nil
+}
+
+// body parses a statement list in braces
+func (p *dotParser) body() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.is("}") {
+		if p.pos >= len(p.tokens) {
+			return p.errorf("expected }")
+		}
+		if err := p.stmt(); err != nil {
+			return err
+		}
+		if p.is(";") {
+			p.pos++
+		}
+	}
+	p.pos++
+	return nil
+}
+
+func (p *dotParser) stmt() error {
+	switch {
+	case p.is("graph") || p.is("node") || p.is("edge"):
+		p.pos++
+		return p.attrList(true)
+	case p.is("subgraph") || p.is("{"):
+		if err := p.subgraph(); err != nil {
+			return err
+		}
+		return p.edgeRHS()
+	}
+	if err := p.id(); err != nil {
+		return err
+	}
+	if p.is("=") {
+		p.pos++
+		return p.id()
+	}
+	if err := p.port(); err != nil {
+		return err
+	}
+	return p.edgeRHS()
+}
+
+func (p *dotParser) subgraph() error {
+	if p.is("subgraph") {
+		p.pos++
+		if !p.is("{") {
+			if err := p.id(); err != nil {
+				return err
+			}
+		}
+	}
+	return p.body()
+}
+
+func (p *dotParser) port() error {
+	for i := 0; i < 2 && p.is(":"); i++ {
+		p.pos++
+		if err := p.id(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// edgeRHS parses the rest of an edge statement, if any, and its attributes
+func (p *dotParser) edgeRHS() error {
+	for p.is("->") || p.is("--") {
+		if !p.is(p.edgeOp) {
+			return p.errorf("expected %s as edge operator", p.edgeOp)
+		}
+		p.pos++
+		if p.is("subgraph") || p.is("{") {
+			if err := p.subgraph(); err != nil {
+				return err
+			}
+		} else {
+			if err := p.id(); err != nil {
+				return err
+			}
+			if err := p.port(); err != nil {
+				return err
+			}
+		}
+	}
+	return p.attrList(false)
+}
+
+func (p *dotParser) attrList(required bool) error {
+	if required && !p.is("[") {
+		return p.errorf("expected [")
+	}
+	for p.is("[") {
+		p.pos++
+		for !p.is("]") {
+			if err := p.id(); err != nil {
+				return err
+			}
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			if err := p.id(); err != nil {
+				return err
+			}
+			if p.is(",") || p.is(";") {
+				p.pos++
+			}
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// validateDot reports whether src is a well-formed DOT graph
+func validateDot(src string) error {
+	tokens, err := lexDot(src)
+	if err != nil {
+		return err
+	}
+	p := &dotParser{tokens: tokens}
+	return p.graph()
+}
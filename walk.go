@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"go/build"
+	"io"
+	"os"
+)
+
+// walkOptions selects how walk resolves packages and what it follows. The
+// zero value resolves with build.Import from the current directory and
+// follows every non-test import outside the standard library.
+type walkOptions struct {
+	// Dir is the directory relative import paths and modules resolve from
+	Dir string
+	// Import resolves an import path from the directory of the importing
+	// package, build.Import if nil
+	Import func(importPath, srcDir string) (*build.Package, error)
+	// Ignore leaves a package out: it gets no node, the edges to it are
+	// dropped and its imports are not followed through it. Nil keeps all.
+	Ignore func(pkg *build.Package) bool
+	// Imports returns the imports of a package to follow, pkg.Imports if nil
+	Imports func(pkg *build.Package) []string
+	// StdlibEdges also follows the imports of standard library packages
+	StdlibEdges bool
+}
+
+// walk resolves the root packages and everything they import, and hands
+// each package to visitNode and each import to visitEdge as soon as it is
+// known, without holding on to the graph: memory grows with the number of
+// import paths seen and the packages waiting to be visited, not with the
+// packages and imports already handed over.
+//
+// Packages are visited breadth-first from the roots, in the order given,
+// and their imports in the order of opts.Imports. visitNode is called once
+// per package before the visitEdge calls for its imports, so an edge can
+// name a package whose node comes later. A package that fails to resolve
+// gets a node with Error set, before the first edge to it, and no edges of
+// its own. The first error returned by a callback stops the walk and is
+// returned as is.
+func walk(roots []string, opts walkOptions, visitNode func(*node) error, visitEdge func(*edge) error) error {
+	importPkg := opts.Import
+	if importPkg == nil {
+		importPkg = func(importPath, srcDir string) (*build.Package, error) {
+			return build.Import(importPath, srcDir, 0)
+		}
+	}
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	importsOf := opts.Imports
+	if importsOf == nil {
+		importsOf = func(pkg *build.Package) []string { return pkg.Imports }
+	}
+
+	// seen holds whether every import path met so far is visible, and
+	// pending the packages resolved but not visited yet
+	const (
+		visible = iota
+		hidden
+		failed
+	)
+	seen := make(map[string]int)
+	pending := make(map[string]*build.Package)
+	var queue []string
+	resolve := func(importPath, srcDir string) (string, int, error) {
+		// an import path seen before is the same package, unless it is
+		// relative or vendored, which an import path of its own tells
+		if state, ok := seen[importPath]; ok && !build.IsLocalImport(importPath) {
+			return importPath, state, nil
+		}
+		pkg, err := importPkg(importPath, srcDir)
+		if err != nil {
+			seen[importPath] = failed
+			return importPath, failed, visitNode(&node{ID: importPath, Error: err.Error(), Color: "red"})
+		}
+		if build.IsLocalImport(pkg.ImportPath) {
+			pkg.ImportPath = moduleImportPath(pkg.Dir, pkg.ImportPath)
+		}
+		id := pkg.ImportPath
+		if state, ok := seen[id]; ok {
+			return id, state, nil
+		}
+		if opts.Ignore != nil && opts.Ignore(pkg) {
+			seen[id] = hidden
+			return id, hidden, nil
+		}
+		seen[id] = visible
+		pending[id] = pkg
+		queue = append(queue, id)
+		return id, visible, nil
+	}
+
+	dependency := make(map[string]string)
+	for _, root := range roots {
+		id, state, err := resolve(root, dir)
+		if err != nil {
+			return err
+		}
+		if state == visible {
+			dependency[id] = "root"
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		pkg := pending[id]
+		delete(pending, id)
+		dep := dependency[id]
+		delete(dependency, id)
+		if dep == "" {
+			dep = "transitive"
+		}
+
+		n := &node{ID: id, Module: moduleOf(pkg), Stdlib: pkg.Goroot, Cgo: len(pkg.CgoFiles) > 0, Dependency: dep, Color: "paleturquoise"}
+		var err error
+		if n.Files, n.Lines, err = packageSize(pkg); err != nil {
+			logger.Warn("failed to measure package", "pkg", id, "err", err)
+		}
+		switch {
+		case n.Stdlib:
+			n.Color = "palegreen"
+		case n.Cgo:
+			n.Color = "darkgoldenrod1"
+		}
+		if err = visitNode(n); err != nil {
+			return err
+		}
+		if pkg.Goroot && !opts.StdlibEdges {
+			continue
+		}
+		blank := blankSpecs(parseImportSpecs(pkg, append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...)))
+		for _, imp := range importsOf(pkg) {
+			if imp == "C" {
+				continue
+			}
+			to, state, err := resolve(imp, pkg.Dir)
+			if err != nil {
+				return err
+			}
+			if state == hidden {
+				continue
+			}
+			if dep == "root" && state == visible && dependency[to] == "" {
+				dependency[to] = "direct"
+			}
+			_, isBlank := blank[imp]
+			if err := visitEdge(&edge{From: id, To: to, Blank: isBlank}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeStream writes the graph of the roots to w as JSON lines of
+// {"node": ...} and {"edge": ...} objects, by a walk with the filters and the
+// build settings of the command line, so that consumers can process the
+// graph as it is read. The objects are entries of -format json with only the
+// fields the walk knows: id, module, stdlib, cgo, dependency, files, lines,
+// color and error of the nodes, and from, to and blank of the edges.
+func writeStream(w io.Writer, roots []string, dir string) error {
+	enc := json.NewEncoder(w)
+	opts := walkOptions{
+		Dir:     dir,
+		Import:  importPackage,
+		Imports: imports,
+		Ignore: func(pkg *build.Package) bool {
+			if reason := ignoreReason(pkg); reason != "" {
+				skip(pkg.ImportPath, reason)
+				return true
+			}
+			setBasePath(pkg)
+			return false
+		},
+		StdlibEdges: *stdlibEdges,
+	}
+	return walk(roots, opts, func(n *node) error {
+		if n.Error != "" {
+			unresolved[n.ID] = errors.New(n.Error)
+		}
+		return enc.Encode(map[string]*node{"node": n})
+	}, func(e *edge) error {
+		return enc.Encode(map[string]*edge{"edge": e})
+	})
+}
+
+// streamConflicts returns the flags of the command line that need the whole
+// graph, the views, overlays and -anonymize, and so do not go with -format
+// jsonl
+func streamConflicts() []string {
+	var names []string
+	for _, name := range append(append([]string(nil), viewFlags...), "anonymize", "granularity", "expand") {
+		if f := flag.Lookup(name); f.Value.String() != f.DefValue {
+			names = append(names, "-"+name)
+		}
+	}
+	for _, name := range enabledOverlays() {
+		names = append(names, "-"+name)
+	}
+	return names
+}
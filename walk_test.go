@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"testing"
+)
+
+// fakeImport resolves the import paths of the map to packages importing
+// the listed paths, and fails for the others
+func fakeImport(deps map[string][]string) func(string, string) (*build.Package, error) {
+	return func(importPath, srcDir string) (*build.Package, error) {
+		imps, ok := deps[importPath]
+		if !ok {
+			return nil, fmt.Errorf("cannot find package %q", importPath)
+		}
+		return &build.Package{ImportPath: importPath, Imports: imps}, nil
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	deps := map[string][]string{
+		"app":      {"app/api", "missing", "app/util"},
+		"app/api":  {"app/util", "missing"},
+		"app/util": nil,
+	}
+	var got []string
+	err := walk([]string{"app"}, walkOptions{Dir: "/", Import: fakeImport(deps)}, func(n *node) error {
+		got = append(got, "node "+n.ID+" "+choose(n.Error != "", "error", n.Dependency))
+		return nil
+	}, func(e *edge) error {
+		got = append(got, "edge "+e.From+" "+e.To)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"node app root",
+		"edge app app/api",
+		"node missing error",
+		"edge app missing",
+		"edge app app/util",
+		"node app/api direct",
+		"edge app/api app/util",
+		"edge app/api missing",
+		"node app/util direct",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestWalkIgnoreAndCallbackError(t *testing.T) {
+	deps := map[string][]string{"app": {"app/mock", "app/util"}, "app/mock": {"app/util"}, "app/util": nil}
+	opts := walkOptions{
+		Dir:    "/",
+		Import: fakeImport(deps),
+		Ignore: func(pkg *build.Package) bool { return pkg.ImportPath == "app/mock" },
+	}
+	stop := errors.New("stop")
+	var nodes, edges int
+	err := walk([]string{"app"}, opts, func(n *node) error {
+		if n.ID == "app/mock" {
+			t.Errorf("ignored package got a node")
+		}
+		nodes++
+		return nil
+	}, func(e *edge) error {
+		if e.To == "app/mock" {
+			t.Errorf("kept the edge to an ignored package")
+		}
+		edges++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("walk returned %v, want the callback error", err)
+	}
+	if nodes != 1 || edges != 1 {
+		t.Errorf("got %d nodes and %d edges before stopping, want 1 and 1", nodes, edges)
+	}
+}
+
+func TestWalkImportsEveryPackageOnce(t *testing.T) {
+	deps := map[string][]string{"app": {"app/a", "app/b", "missing"}, "app/a": {"app/b", "missing"}, "app/b": {"missing"}}
+	calls := make(map[string]int)
+	fake := fakeImport(deps)
+	opts := walkOptions{Dir: "/", Import: func(importPath, srcDir string) (*build.Package, error) {
+		calls[importPath]++
+		return fake(importPath, srcDir)
+	}}
+	nop := func(*node) error { return nil }
+	if err := walk([]string{"app"}, opts, nop, func(*edge) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for path, n := range calls {
+		if n != 1 {
+			t.Errorf("imported %s %d times", path, n)
+		}
+	}
+}
+
+func TestStreamRefusesAnonymize(t *testing.T) {
+	saved := *anonymize
+	t.Cleanup(func() { *anonymize = saved })
+	*anonymize = false
+	if conflicts := streamConflicts(); len(conflicts) > 0 {
+		t.Fatalf("conflicts %v without flags", conflicts)
+	}
+	*anonymize = true
+	if conflicts := streamConflicts(); len(conflicts) != 1 || conflicts[0] != "-anonymize" {
+		t.Errorf("-anonymize -format jsonl conflicts on %v, want -anonymize", conflicts)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// without returns a copy of the graph without the given nodes and their
+// edges. Nodes and edges are shared with the original.
+func (g *graph) without(remove map[string]bool) *graph {
+	c := &graph{Namespace: g.Namespace}
+	for _, root := range g.Roots {
+		if !remove[root] {
+			c.Roots = append(c.Roots, root)
+		}
+	}
+	for _, n := range g.Nodes {
+		if !remove[n.ID] {
+			c.Nodes = append(c.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if !remove[e.From] && !remove[e.To] {
+			c.Edges = append(c.Edges, e)
+		}
+	}
+	return c
+}
+
+// reachableFromRoots returns the nodes reachable from any root
+func (g *graph) reachableFromRoots() map[string]bool {
+	reached := make(map[string]bool)
+	for _, root := range g.Roots {
+		for id := range g.reachable(root) {
+			reached[id] = true
+		}
+	}
+	return reached
+}
+
+func (g *graph) maxDepth() int {
+	max := 0
+	for _, root := range g.Roots {
+		if d := g.depth(root); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// runWhatIf prints what removing the packages given by -remove would do to
+// the graph: which packages break because they import one of them, which
+// ones are no longer needed, and how depth and external modules change
+func runWhatIf(g *graph) error {
+	if *removePackages == "" {
+		return fmt.Errorf("whatif needs -remove")
+	}
+	remove := stringSet(sanitizeCSV(*removePackages))
+	found := make(map[string]bool)
+	for _, n := range g.Nodes {
+		found[n.ID] = true
+	}
+	for id := range remove {
+		if !found[id] {
+			return fmt.Errorf("-remove %s: not in the graph", id)
+		}
+	}
+	after := g.without(remove)
+
+	broken := make(map[string]bool)
+	for _, e := range g.Edges {
+		if remove[e.To] && !remove[e.From] {
+			broken[e.From] = true
+		}
+	}
+	var unreachable []string
+	reached := after.reachableFromRoots()
+	for id := range g.reachableFromRoots() {
+		if !reached[id] && !remove[id] {
+			unreachable = append(unreachable, id)
+		}
+	}
+	sort.Strings(unreachable)
+	after.keep(reached)
+
+	list := func(title string, ids []string) {
+		fmt.Printf("%s: %d\n", title, len(ids))
+		for _, id := range ids {
+			fmt.Printf("    %s\n", id)
+		}
+	}
+	var brokenList []string
+	for id := range broken {
+		brokenList = append(brokenList, id)
+	}
+	sort.Strings(brokenList)
+	list("broken, importing a removed package", brokenList)
+	list("no longer reachable from the roots", unreachable)
+	fmt.Printf("depth: %d -> %d\n", g.maxDepth(), after.maxDepth())
+	fmt.Printf("external modules: %d -> %d\n", len(g.externalModules()), len(after.externalModules()))
+	fmt.Printf("cycles: %d -> %d\n", len(g.cycles()), len(after.cycles()))
+	return nil
+}